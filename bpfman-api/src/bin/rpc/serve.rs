@@ -20,7 +20,7 @@ use tokio::{
     task::{JoinHandle, JoinSet},
 };
 use tokio_stream::wrappers::UnixListenerStream;
-use tonic::transport::Server;
+use tonic::{codec::CompressionEncoding, transport::Server};
 
 use crate::{rpc::BpfmanLoader, storage::StorageManager};
 
@@ -30,7 +30,10 @@ pub async fn serve(csi_support: bool, timeout: u64, socket_path: &Path) -> anyho
     let shutdown_handle = tokio::spawn(shutdown_handler(timeout, shutdown_tx));
 
     let loader = BpfmanLoader::new();
-    let service = BpfmanServer::new(loader);
+    // Clients may compress large requests. Responses are left uncompressed:
+    // grpc-go clients advertise every compressor registered in the process,
+    // so compressing whenever gzip is accepted would give them no opt-out.
+    let service = BpfmanServer::new(loader).accept_compressed(CompressionEncoding::Gzip);
 
     let mut listeners: Vec<_> = Vec::new();
 
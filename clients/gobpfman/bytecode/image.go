@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bytecode
+
+import (
+	"fmt"
+
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+)
+
+// ImagePullPolicy mirrors the pull policies understood by the bpfman daemon.
+// The values match BytecodeImage.ImagePullPolicy on the wire.
+type ImagePullPolicy int32
+
+const (
+	PullAlways       ImagePullPolicy = 0
+	PullIfNotPresent ImagePullPolicy = 1
+	PullNever        ImagePullPolicy = 2
+)
+
+func (p ImagePullPolicy) String() string {
+	switch p {
+	case PullAlways:
+		return "Always"
+	case PullIfNotPresent:
+		return "IfNotPresent"
+	case PullNever:
+		return "Never"
+	default:
+		return fmt.Sprintf("ImagePullPolicy(%d)", int32(p))
+	}
+}
+
+// ParseImagePullPolicy converts a policy name, as used in the CRDs and the
+// bpfman CLI, into an ImagePullPolicy.
+func ParseImagePullPolicy(s string) (ImagePullPolicy, error) {
+	switch s {
+	case "Always":
+		return PullAlways, nil
+	case "IfNotPresent":
+		return PullIfNotPresent, nil
+	case "Never":
+		return PullNever, nil
+	default:
+		return PullAlways, fmt.Errorf("invalid image pull policy %q", s)
+	}
+}
+
+// ShouldPull reports whether an image has to be pulled given the policy and
+// whether it is already present locally. It returns an error when the image
+// is required but the policy forbids pulling it, matching the daemon.
+func (p ImagePullPolicy) ShouldPull(present bool) (bool, error) {
+	switch p {
+	case PullAlways:
+		return true, nil
+	case PullIfNotPresent:
+		return !present, nil
+	case PullNever:
+		if !present {
+			return false, fmt.Errorf("image not present and pull policy is %s", p)
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid image pull policy %d", int32(p))
+	}
+}
+
+// Image describes a bytecode image to be loaded by bpfman.
+type Image struct {
+	Reference  Reference
+	PullPolicy ImagePullPolicy
+	Username   *string
+	Password   *string
+}
+
+// NewImage parses url and checks it against policy, returning an Image with
+// the given pull policy.
+func NewImage(url string, pullPolicy ImagePullPolicy, policy TagPolicy) (Image, error) {
+	ref, err := ParseReference(url)
+	if err != nil {
+		return Image{}, err
+	}
+	if err := ref.Validate(policy); err != nil {
+		return Image{}, err
+	}
+
+	return Image{
+		Reference:  ref,
+		PullPolicy: pullPolicy,
+	}, nil
+}
+
+// BytecodeLocation converts the image into the form used in a LoadRequest.
+func (i Image) BytecodeLocation() *gobpfman.BytecodeLocation {
+	return &gobpfman.BytecodeLocation{
+		Location: &gobpfman.BytecodeLocation_Image{Image: &gobpfman.BytecodeImage{
+			Url:             i.Reference.String(),
+			ImagePullPolicy: int32(i.PullPolicy),
+			Username:        i.Username,
+			Password:        i.Password,
+		}},
+	}
+}
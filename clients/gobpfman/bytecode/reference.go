@@ -0,0 +1,176 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bytecode parses and validates references to eBPF bytecode packaged
+// in OCI container images and converts them into the BytecodeLocation used in
+// bpfman LoadRequests. Parsing follows the same defaults as the bpfman
+// daemon, so a reference accepted here resolves to the same image there.
+package bytecode
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultRegistry is used when a reference doesn't name a registry.
+	DefaultRegistry = "docker.io"
+	// DefaultTag is used when a reference has neither a tag nor a digest.
+	DefaultTag = "latest"
+
+	// Docker Hub images without a namespace live under "library/".
+	defaultNamespace = "library"
+)
+
+var (
+	repositoryComponentRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	tagRegexp                 = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegexp              = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]{32,}$`)
+)
+
+// TagPolicy restricts which references are acceptable.
+type TagPolicy int
+
+const (
+	// TagPolicyAny accepts any tag or digest.
+	TagPolicyAny TagPolicy = iota
+	// TagPolicyNoLatest rejects references that resolve to the "latest" tag
+	// without a digest.
+	TagPolicyNoLatest
+	// TagPolicyDigestRequired only accepts references pinned by digest.
+	TagPolicyDigestRequired
+)
+
+func (p TagPolicy) String() string {
+	switch p {
+	case TagPolicyAny:
+		return "Any"
+	case TagPolicyNoLatest:
+		return "NoLatest"
+	case TagPolicyDigestRequired:
+		return "DigestRequired"
+	default:
+		return fmt.Sprintf("TagPolicy(%d)", int(p))
+	}
+}
+
+// Reference is a parsed OCI image reference.
+type Reference struct {
+	Registry   string
+	Repository string
+	// Tag is empty when the reference is pinned by digest only.
+	Tag    string
+	Digest string
+}
+
+// ParseReference parses an image reference such as
+// "quay.io/bpfman-bytecode/go-xdp-counter:latest", filling in the default
+// registry, namespace and tag the same way the bpfman daemon does.
+func ParseReference(url string) (Reference, error) {
+	var ref Reference
+
+	if url == "" {
+		return ref, fmt.Errorf("image reference is empty")
+	}
+	if strings.Contains(url, "://") {
+		return ref, fmt.Errorf("image reference %q must not include a scheme", url)
+	}
+
+	name := url
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !digestRegexp.MatchString(ref.Digest) {
+			return ref, fmt.Errorf("invalid digest %q in image reference %q", ref.Digest, url)
+		}
+	}
+
+	// A colon after the last slash separates the tag; a colon before it is
+	// a registry port.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+		if !tagRegexp.MatchString(ref.Tag) {
+			return ref, fmt.Errorf("invalid tag %q in image reference %q", ref.Tag, url)
+		}
+	}
+
+	// The first component is a registry only if it looks like a host name.
+	if i := strings.Index(name, "/"); i >= 0 &&
+		(strings.ContainsAny(name[:i], ".:") || name[:i] == "localhost") {
+		ref.Registry = name[:i]
+		ref.Repository = name[i+1:]
+	} else {
+		ref.Registry = DefaultRegistry
+		ref.Repository = name
+	}
+
+	if ref.Registry == DefaultRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = defaultNamespace + "/" + ref.Repository
+	}
+
+	for _, component := range strings.Split(ref.Repository, "/") {
+		if !repositoryComponentRegexp.MatchString(component) {
+			return ref, fmt.Errorf("invalid repository %q in image reference %q", ref.Repository, url)
+		}
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = DefaultTag
+	}
+
+	return ref, nil
+}
+
+// String returns the fully qualified form of the reference.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// IsDigestPinned returns true if the reference identifies an immutable image.
+func (r Reference) IsDigestPinned() bool {
+	return r.Digest != ""
+}
+
+// Validate checks the reference against a tag policy.
+func (r Reference) Validate(policy TagPolicy) error {
+	switch policy {
+	case TagPolicyAny:
+		return nil
+	case TagPolicyNoLatest:
+		if !r.IsDigestPinned() && r.Tag == DefaultTag {
+			return fmt.Errorf("image %s uses the %q tag, which is not allowed by the %s policy",
+				r, DefaultTag, policy)
+		}
+		return nil
+	case TagPolicyDigestRequired:
+		if !r.IsDigestPinned() {
+			return fmt.Errorf("image %s is not pinned by digest, which is required by the %s policy",
+				r, policy)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown tag policy %d", policy)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bytecode
+
+import (
+	"strings"
+	"testing"
+)
+
+// imageContentKey mirrors get_image_content_key() in
+// bpfman/src/oci_utils/image_manager.rs, which names the directory the daemon
+// stores an image under.
+func imageContentKey(ref Reference) string {
+	tag := ref.Tag
+	if tag == "" {
+		tag = ref.Digest
+	}
+	return ref.Registry + "_" + strings.ReplaceAll(ref.Repository, "/", "_") + "_" + tag
+}
+
+// The cases are those of test_good_image_content_key() in
+// bpfman/src/oci_utils/image_manager.rs, so a reference parsed here resolves
+// to the same image as in the daemon.
+func TestParseReferenceMatchesDaemon(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{input: "busybox", output: "docker.io_library_busybox_latest"},
+		{input: "quay.io/busybox", output: "quay.io_busybox_latest"},
+		{input: "docker.io/test:tag", output: "docker.io_library_test_tag"},
+		{input: "quay.io/test:5000", output: "quay.io_test_5000"},
+		{input: "test.com/repo:tag", output: "test.com_repo_tag"},
+		{
+			input:  "test.com/repo@sha256:ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			output: "test.com_repo_sha256:ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		},
+	}
+
+	for _, tt := range tests {
+		ref, err := ParseReference(tt.input)
+		if err != nil {
+			t.Errorf("ParseReference(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got := imageContentKey(ref); got != tt.output {
+			t.Errorf("ParseReference(%q) resolved to %q, want %q", tt.input, got, tt.output)
+		}
+	}
+}
+
+func TestTagPolicyString(t *testing.T) {
+	tests := []struct {
+		policy TagPolicy
+		want   string
+	}{
+		{TagPolicyAny, "Any"},
+		{TagPolicyNoLatest, "NoLatest"},
+		{TagPolicyDigestRequired, "DigestRequired"},
+		{TagPolicy(7), "TagPolicy(7)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.String(); got != tt.want {
+			t.Errorf("TagPolicy(%d).String() = %q, want %q", int(tt.policy), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// bpfman-convert reads an eBPF ELF object, and optionally the bpftool command
+// line that was used to attach it, and prints the equivalent bpfman *Program
+// CRDs or gobpfman LoadRequests. It is intended to help move programs that
+// are loaded by ad-hoc bpftool/libbpf scripts under bpfman management.
+//
+// Examples:
+//
+//	bpfman-convert -file xdp_counter.o -iface eth0
+//	bpfman-convert -file tc_counter.o -bpftool "net attach tcx_egress pinned /sys/fs/bpf/tc dev eth0"
+//	bpfman-convert -file kprobe_counter.o -format loadrequest
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	bpfmaniov1alpha1 "github.com/bpfman/bpfman-operator/apis/v1alpha1"
+	"github.com/bpfman/bpfman/clients/gobpfman/bytecode"
+	"github.com/bpfman/bpfman/clients/gobpfman/compat/ciliumebpf"
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	"github.com/cilium/ebpf"
+	"google.golang.org/protobuf/encoding/protojson"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FormatYaml        = "yaml"
+	FormatLoadRequest = "loadrequest"
+)
+
+type options struct {
+	file       string
+	image      string
+	pullPolicy string
+	program    string
+	format     string
+	iface      string
+	direction  string
+	priority   int
+	fnName     string
+	target     string
+	bpftool    string
+}
+
+func main() {
+	var opts options
+
+	flag.StringVar(&opts.file, "file", "", "Path of the eBPF ELF object to convert. Required.")
+	flag.StringVar(&opts.image, "image", "",
+		"Bytecode image URL to reference instead of \"file\" in the output. Optional.")
+	flag.StringVar(&opts.pullPolicy, "pull_policy", bytecode.PullIfNotPresent.String(),
+		"Pull policy of \"image\" (Always, IfNotPresent, Never).")
+	flag.StringVar(&opts.program, "program", "",
+		"Name of the program in the ELF to convert. Defaults to all programs.")
+	flag.StringVar(&opts.format, "format", FormatYaml,
+		"Output format (yaml, loadrequest).")
+	flag.StringVar(&opts.iface, "iface", "", "Interface for XDP and TC programs.")
+	flag.StringVar(&opts.direction, "direction", "ingress",
+		"Direction for TC programs (ingress, egress).")
+	flag.IntVar(&opts.priority, "priority", 50, "Priority for XDP and TC programs.")
+	flag.StringVar(&opts.fnName, "fn_name", "",
+		"Function to attach kprobe, uprobe, fentry and fexit programs to, if not\n"+
+			"given by the ELF section name.")
+	flag.StringVar(&opts.target, "target", "",
+		"Library or executable for uprobe programs, if not given by the ELF section\n"+
+			"name.")
+	flag.StringVar(&opts.bpftool, "bpftool", "",
+		"bpftool command line used to attach the program today. The \"dev\" and\n"+
+			"attach type arguments are used to fill in the interface and direction.\n"+
+			"Example: -bpftool \"net attach xdp id 42 dev eth0\"")
+	flag.Parse()
+
+	if err := run(opts, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run converts the programs selected by opts and writes them to w.
+func run(opts options, w io.Writer) error {
+	if opts.file == "" {
+		return fmt.Errorf("\"file\" is required")
+	}
+	if opts.bpftool != "" {
+		if err := applyBpftool(&opts, opts.bpftool); err != nil {
+			return err
+		}
+	}
+
+	if opts.image != "" {
+		if _, err := bytecodeImage(opts); err != nil {
+			return err
+		}
+	}
+
+	spec, err := ebpf.LoadCollectionSpec(opts.file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", opts.file, err)
+	}
+
+	names := make([]string, 0, len(spec.Programs))
+	for name := range spec.Programs {
+		if opts.program == "" || opts.program == name {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no program named %q in %s", opts.program, opts.file)
+	}
+	sort.Strings(names)
+
+	var written bool
+	var skipped []string
+	for _, name := range names {
+		progSpec := spec.Programs[name]
+
+		var out []byte
+		switch opts.format {
+		case FormatYaml:
+			out, err = toProgramYaml(name, progSpec, opts)
+			if written {
+				out = append([]byte("---\n"), out...)
+			}
+		case FormatLoadRequest:
+			var req *gobpfman.LoadRequest
+			req, err = toLoadRequest(name, progSpec, opts)
+			if err == nil {
+				out, err = protojson.MarshalOptions{Multiline: true}.Marshal(req)
+				out = append(out, '\n')
+			}
+		default:
+			return fmt.Errorf("invalid format (%s). valid options are yaml or loadrequest", opts.format)
+		}
+		if err != nil {
+			log.Printf("skipping program %s: %v", name, err)
+			skipped = append(skipped, name)
+			continue
+		}
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+		written = true
+	}
+
+	if len(skipped) > 0 {
+		return fmt.Errorf("%d of %d programs could not be converted: %s",
+			len(skipped), len(names), strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+// applyBpftool fills in attach options from a bpftool "net attach" or "prog
+// attach" command line.
+func applyBpftool(opts *options, cmdline string) error {
+	args := strings.Fields(strings.TrimPrefix(strings.TrimSpace(cmdline), "bpftool"))
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "dev":
+			if i+1 >= len(args) {
+				return fmt.Errorf("bpftool command line is missing the device after \"dev\"")
+			}
+			opts.iface = args[i+1]
+			i++
+		case "ingress", "tcx_ingress":
+			opts.direction = "ingress"
+		case "egress", "tcx_egress":
+			opts.direction = "egress"
+		}
+	}
+	return nil
+}
+
+func (opts options) attachOptions() ciliumebpf.AttachOptions {
+	return ciliumebpf.AttachOptions{
+		Iface:     opts.iface,
+		Priority:  int32(opts.priority),
+		Direction: opts.direction,
+		FnName:    opts.fnName,
+		Target:    opts.target,
+	}
+}
+
+// bytecodeImage returns the "image" option with its pull policy.
+func bytecodeImage(opts options) (bytecode.Image, error) {
+	pullPolicy, err := bytecode.ParseImagePullPolicy(opts.pullPolicy)
+	if err != nil {
+		return bytecode.Image{}, err
+	}
+	return bytecode.NewImage(opts.image, pullPolicy, bytecode.TagPolicyAny)
+}
+
+func bytecodeSelector(opts options) (bpfmaniov1alpha1.BytecodeSelector, error) {
+	if opts.image != "" {
+		image, err := bytecodeImage(opts)
+		if err != nil {
+			return bpfmaniov1alpha1.BytecodeSelector{}, err
+		}
+		return bpfmaniov1alpha1.BytecodeSelector{
+			Image: &bpfmaniov1alpha1.BytecodeImage{
+				Url:             opts.image,
+				ImagePullPolicy: bpfmaniov1alpha1.PullPolicy(image.PullPolicy.String()),
+			},
+		}, nil
+	}
+	path := opts.file
+	return bpfmaniov1alpha1.BytecodeSelector{Path: &path}, nil
+}
+
+// toProgramYaml renders the program as the matching bpfman.io *Program CRD.
+func toProgramYaml(name string, progSpec *ebpf.ProgramSpec, opts options) ([]byte, error) {
+	objectMeta := metav1.ObjectMeta{
+		Name: strings.ToLower(strings.ReplaceAll(name, "_", "-")),
+	}
+	programCommon := bpfmaniov1alpha1.BpfProgramCommon{
+		BpfFunctionName: name,
+	}
+	attachOpts := opts.attachOptions()
+	sectionType := ciliumebpf.SectionType(progSpec)
+	selector, err := bytecodeSelector(opts)
+	if err != nil {
+		return nil, err
+	}
+	appCommon := bpfmaniov1alpha1.BpfAppCommon{
+		NodeSelector: metav1.LabelSelector{},
+		ByteCode:     selector,
+	}
+	typeMeta := func(kind string) metav1.TypeMeta {
+		return metav1.TypeMeta{
+			APIVersion: bpfmaniov1alpha1.SchemeGroupVersion.String(),
+			Kind:       kind,
+		}
+	}
+	interfaces := func() (bpfmaniov1alpha1.InterfaceSelector, error) {
+		if opts.iface == "" {
+			return bpfmaniov1alpha1.InterfaceSelector{}, fmt.Errorf("interface is required")
+		}
+		return bpfmaniov1alpha1.InterfaceSelector{Interfaces: &[]string{opts.iface}}, nil
+	}
+
+	var obj interface{}
+	switch sectionType {
+	case "xdp":
+		selector, err := interfaces()
+		if err != nil {
+			return nil, err
+		}
+		obj = &bpfmaniov1alpha1.XdpProgram{
+			TypeMeta:   typeMeta("XdpProgram"),
+			ObjectMeta: objectMeta,
+			Spec: bpfmaniov1alpha1.XdpProgramSpec{
+				XdpProgramInfo: bpfmaniov1alpha1.XdpProgramInfo{
+					BpfProgramCommon:  programCommon,
+					InterfaceSelector: selector,
+					Priority:          int32(opts.priority),
+				},
+				BpfAppCommon: appCommon,
+			},
+		}
+	case "tc", "classifier":
+		selector, err := interfaces()
+		if err != nil {
+			return nil, err
+		}
+		obj = &bpfmaniov1alpha1.TcProgram{
+			TypeMeta:   typeMeta("TcProgram"),
+			ObjectMeta: objectMeta,
+			Spec: bpfmaniov1alpha1.TcProgramSpec{
+				TcProgramInfo: bpfmaniov1alpha1.TcProgramInfo{
+					BpfProgramCommon:  programCommon,
+					InterfaceSelector: selector,
+					Priority:          int32(opts.priority),
+					Direction:         opts.direction,
+				},
+				BpfAppCommon: appCommon,
+			},
+		}
+	case "tracepoint", "tp":
+		obj = &bpfmaniov1alpha1.TracepointProgram{
+			TypeMeta:   typeMeta("TracepointProgram"),
+			ObjectMeta: objectMeta,
+			Spec: bpfmaniov1alpha1.TracepointProgramSpec{
+				TracepointProgramInfo: bpfmaniov1alpha1.TracepointProgramInfo{
+					BpfProgramCommon: programCommon,
+					Names:            []string{progSpec.AttachTo},
+				},
+				BpfAppCommon: appCommon,
+			},
+		}
+	case "kprobe", "kretprobe":
+		obj = &bpfmaniov1alpha1.KprobeProgram{
+			TypeMeta:   typeMeta("KprobeProgram"),
+			ObjectMeta: objectMeta,
+			Spec: bpfmaniov1alpha1.KprobeProgramSpec{
+				KprobeProgramInfo: bpfmaniov1alpha1.KprobeProgramInfo{
+					BpfProgramCommon: programCommon,
+					FunctionName:     ciliumebpf.FnName(progSpec, attachOpts),
+					RetProbe:         sectionType == "kretprobe",
+				},
+				BpfAppCommon: appCommon,
+			},
+		}
+	case "uprobe", "uretprobe":
+		obj = &bpfmaniov1alpha1.UprobeProgram{
+			TypeMeta:   typeMeta("UprobeProgram"),
+			ObjectMeta: objectMeta,
+			Spec: bpfmaniov1alpha1.UprobeProgramSpec{
+				UprobeProgramInfo: bpfmaniov1alpha1.UprobeProgramInfo{
+					BpfProgramCommon: programCommon,
+					FunctionName:     ciliumebpf.FnName(progSpec, attachOpts),
+					Target:           ciliumebpf.Target(progSpec, attachOpts),
+					RetProbe:         sectionType == "uretprobe",
+				},
+				BpfAppCommon: appCommon,
+			},
+		}
+	case "fentry":
+		obj = &bpfmaniov1alpha1.FentryProgram{
+			TypeMeta:   typeMeta("FentryProgram"),
+			ObjectMeta: objectMeta,
+			Spec: bpfmaniov1alpha1.FentryProgramSpec{
+				FentryProgramInfo: bpfmaniov1alpha1.FentryProgramInfo{
+					BpfProgramCommon: programCommon,
+					FunctionName:     ciliumebpf.FnName(progSpec, attachOpts),
+				},
+				BpfAppCommon: appCommon,
+			},
+		}
+	case "fexit":
+		obj = &bpfmaniov1alpha1.FexitProgram{
+			TypeMeta:   typeMeta("FexitProgram"),
+			ObjectMeta: objectMeta,
+			Spec: bpfmaniov1alpha1.FexitProgramSpec{
+				FexitProgramInfo: bpfmaniov1alpha1.FexitProgramInfo{
+					BpfProgramCommon: programCommon,
+					FunctionName:     ciliumebpf.FnName(progSpec, attachOpts),
+				},
+				BpfAppCommon: appCommon,
+			},
+		}
+	default:
+		return nil, fmt.Errorf("section type %q is not supported by bpfman", sectionType)
+	}
+
+	return yaml.Marshal(obj)
+}
+
+// toLoadRequest builds the gobpfman LoadRequest that loads the program
+// directly through the bpfman gRPC API.
+func toLoadRequest(name string, progSpec *ebpf.ProgramSpec, opts options) (*gobpfman.LoadRequest, error) {
+	location := &gobpfman.BytecodeLocation{
+		Location: &gobpfman.BytecodeLocation_File{File: opts.file},
+	}
+	if opts.image != "" {
+		image, err := bytecodeImage(opts)
+		if err != nil {
+			return nil, err
+		}
+		location = image.BytecodeLocation()
+	}
+
+	return ciliumebpf.LoadRequest(name, progSpec, location, opts.attachOptions())
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testProgram is a program in an object written by writeObject.
+type testProgram struct {
+	section string
+	name    string
+}
+
+// writeObject writes a minimal eBPF ELF object, as clang would produce for
+// programs that just return, to a temporary file and returns its path. The
+// examples' objects are generated at build time rather than checked in, so
+// the tests build their own.
+func writeObject(t *testing.T, progs ...testProgram) string {
+	t.Helper()
+
+	// r0 = 2 (XDP_PASS, TC_ACT_SHOT); exit
+	insns := []byte{
+		0xb7, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+		0x95, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	type section struct {
+		name    string
+		header  elf.Section64
+		content []byte
+	}
+	var sections []section
+	var shstrtab, strtab bytes.Buffer
+	shstrtab.WriteByte(0)
+	strtab.WriteByte(0)
+	addName := func(b *bytes.Buffer, name string) uint32 {
+		off := uint32(b.Len())
+		b.WriteString(name)
+		b.WriteByte(0)
+		return off
+	}
+	addSection := func(name string, header elf.Section64, content []byte) int {
+		header.Name = addName(&shstrtab, name)
+		header.Size = uint64(len(content))
+		sections = append(sections, section{name, header, content})
+		return len(sections)
+	}
+
+	var symtab bytes.Buffer
+	binary.Write(&symtab, binary.LittleEndian, elf.Sym64{})
+	for _, p := range progs {
+		idx := addSection(p.section, elf.Section64{
+			Type:      uint32(elf.SHT_PROGBITS),
+			Flags:     uint64(elf.SHF_ALLOC | elf.SHF_EXECINSTR),
+			Addralign: 8,
+		}, insns)
+		binary.Write(&symtab, binary.LittleEndian, elf.Sym64{
+			Name:  addName(&strtab, p.name),
+			Info:  elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC),
+			Shndx: uint16(idx),
+			Size:  uint64(len(insns)),
+		})
+	}
+	addSection("license", elf.Section64{
+		Type:      uint32(elf.SHT_PROGBITS),
+		Flags:     uint64(elf.SHF_ALLOC | elf.SHF_WRITE),
+		Addralign: 1,
+	}, []byte("GPL\x00"))
+	strtabIdx := len(sections) + 2
+	addSection(".symtab", elf.Section64{
+		Type:      uint32(elf.SHT_SYMTAB),
+		Link:      uint32(strtabIdx),
+		Info:      1, // index of the first global symbol
+		Addralign: 8,
+		Entsize:   elf.Sym64Size,
+	}, symtab.Bytes())
+	addSection(".strtab", elf.Section64{Type: uint32(elf.SHT_STRTAB), Addralign: 1}, strtab.Bytes())
+	shstrtabIdx := addSection(".shstrtab", elf.Section64{Type: uint32(elf.SHT_STRTAB), Addralign: 1}, nil)
+	sections[shstrtabIdx-1].content = shstrtab.Bytes()
+	sections[shstrtabIdx-1].header.Size = uint64(shstrtab.Len())
+
+	// Section contents follow the ELF header, then the section headers.
+	var body bytes.Buffer
+	off := uint64(binary.Size(elf.Header64{}))
+	for i := range sections {
+		for off%8 != 0 {
+			body.WriteByte(0)
+			off++
+		}
+		sections[i].header.Off = off
+		body.Write(sections[i].content)
+		off += uint64(len(sections[i].content))
+	}
+	for off%8 != 0 {
+		body.WriteByte(0)
+		off++
+	}
+
+	header := elf.Header64{
+		Type:      uint16(elf.ET_REL),
+		Machine:   uint16(elf.EM_BPF),
+		Version:   uint32(elf.EV_CURRENT),
+		Shoff:     off,
+		Ehsize:    uint16(binary.Size(elf.Header64{})),
+		Shentsize: uint16(binary.Size(elf.Section64{})),
+		Shnum:     uint16(len(sections) + 1),
+		Shstrndx:  uint16(shstrtabIdx),
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var obj bytes.Buffer
+	binary.Write(&obj, binary.LittleEndian, header)
+	obj.Write(body.Bytes())
+	binary.Write(&obj, binary.LittleEndian, elf.Section64{})
+	for _, s := range sections {
+		binary.Write(&obj, binary.LittleEndian, s.header)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.o")
+	if err := os.WriteFile(path, obj.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestApplyBpftool(t *testing.T) {
+	tests := []struct {
+		cmdline   string
+		iface     string
+		direction string
+		wantErr   bool
+	}{
+		{cmdline: "net attach xdp id 42 dev eth0", iface: "eth0", direction: "ingress"},
+		{cmdline: "bpftool net attach tcx_egress pinned /sys/fs/bpf/tc dev eth1", iface: "eth1", direction: "egress"},
+		{cmdline: "prog attach pinned /sys/fs/bpf/tc ingress dev eth2", iface: "eth2", direction: "ingress"},
+		{cmdline: "net attach xdp id 42 dev", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		opts := options{direction: "ingress"}
+		err := applyBpftool(&opts, tt.cmdline)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.cmdline)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.cmdline, err)
+			continue
+		}
+		if opts.iface != tt.iface || opts.direction != tt.direction {
+			t.Errorf("%q: got iface %q direction %q, want %q %q",
+				tt.cmdline, opts.iface, opts.direction, tt.iface, tt.direction)
+		}
+	}
+}
+
+func TestRunYaml(t *testing.T) {
+	file := writeObject(t,
+		testProgram{section: "xdp", name: "xdp_stats"},
+		testProgram{section: "kprobe/try_to_wake_up", name: "kprobe_counter"},
+	)
+
+	var out bytes.Buffer
+	opts := options{
+		file:       file,
+		image:      "quay.io/bpfman-bytecode/go-xdp-counter:latest",
+		pullPolicy: "Always",
+		format:     FormatYaml,
+		priority:   50,
+		direction:  "ingress",
+		bpftool:    "net attach xdp id 42 dev eth0",
+	}
+	if err := run(opts, &out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	want := `apiVersion: bpfman.io/v1alpha1
+kind: KprobeProgram
+metadata:
+  creationTimestamp: null
+  name: kprobe-counter
+spec:
+  bpffunctionname: kprobe_counter
+  bytecode:
+    image:
+      imagepullpolicy: Always
+      url: quay.io/bpfman-bytecode/go-xdp-counter:latest
+  func_name: try_to_wake_up
+  mapownerselector: {}
+  nodeselector: {}
+  offset: 0
+  retprobe: false
+status: {}
+---
+apiVersion: bpfman.io/v1alpha1
+kind: XdpProgram
+metadata:
+  creationTimestamp: null
+  name: xdp-stats
+spec:
+  bpffunctionname: xdp_stats
+  bytecode:
+    image:
+      imagepullpolicy: Always
+      url: quay.io/bpfman-bytecode/go-xdp-counter:latest
+  interfaceselector:
+    interfaces:
+    - eth0
+  mapownerselector: {}
+  nodeselector: {}
+  priority: 50
+  proceedon: null
+status: {}
+`
+	if got := out.String(); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunLoadRequest(t *testing.T) {
+	file := writeObject(t, testProgram{section: "classifier", name: "tc_stats"})
+
+	var out bytes.Buffer
+	opts := options{
+		file:      file,
+		format:    FormatLoadRequest,
+		priority:  50,
+		direction: "ingress",
+		bpftool:   "net attach tcx_egress pinned /sys/fs/bpf/tc dev eth0",
+	}
+	if err := run(opts, &out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	want := `{
+  "bytecode": {
+    "file": "` + file + `"
+  },
+  "name": "tc_stats",
+  "programType": 3,
+  "attach": {
+    "tcAttachInfo": {
+      "priority": 50,
+      "iface": "eth0",
+      "direction": "egress"
+    }
+  }
+}
+`
+	// protojson randomly varies its whitespace, so compare without it.
+	normalize := func(s string) string { return strings.Join(strings.Fields(s), "") }
+	if got := out.String(); normalize(got) != normalize(want) {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunSkippedProgram(t *testing.T) {
+	// XDP programs need an interface, so the xdp program can't be converted.
+	file := writeObject(t,
+		testProgram{section: "xdp", name: "xdp_stats"},
+		testProgram{section: "kprobe/try_to_wake_up", name: "kprobe_counter"},
+	)
+
+	var out bytes.Buffer
+	err := run(options{file: file, format: FormatYaml}, &out)
+	if err == nil || !strings.Contains(err.Error(), "xdp_stats") {
+		t.Errorf("expected an error naming xdp_stats, got %v", err)
+	}
+	if !strings.Contains(out.String(), "kind: KprobeProgram") {
+		t.Errorf("convertible programs should still be written, got:\n%s", out.String())
+	}
+	if strings.HasPrefix(out.String(), "---") {
+		t.Errorf("output starts with a document separator:\n%s", out.String())
+	}
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// bpfman-jsonschema generates JSON Schemas for the bpfman gRPC request
+// messages from the gobpfman Go types, so clients in other languages or
+// users of a JSON gateway can validate requests before sending them. The
+// schemas describe the protojson encoding of each message, and accept both
+// the lowerCamelCase and the original proto field names, as protojson does.
+// An example payload is also written for each request.
+//
+// Example:
+//
+//	bpfman-jsonschema -out ./schemas
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+
+	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
+	"github.com/bpfman/bpfman/clients/gobpfman/bytecode"
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	schemaIdBase  = "https://bpfman.io/schemas/v1/"
+)
+
+// requests are the messages a schema is generated for, together with an
+// example payload for each.
+var requests = []proto.Message{
+	&gobpfman.LoadRequest{
+		Bytecode: &gobpfman.BytecodeLocation{
+			Location: &gobpfman.BytecodeLocation_Image{Image: &gobpfman.BytecodeImage{
+				Url: "quay.io/bpfman-bytecode/go-xdp-counter:latest",
+			}},
+		},
+		Name:        "xdp_stats",
+		ProgramType: *bpfmanHelpers.Xdp.Uint32(),
+		Attach: &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_XdpAttachInfo{
+				XdpAttachInfo: &gobpfman.XDPAttachInfo{
+					Priority: 50,
+					Iface:    "eth0",
+				},
+			},
+		},
+		Metadata: map[string]string{"owner": "go-xdp-counter"},
+	},
+	&gobpfman.UnloadRequest{Id: 6371},
+	&gobpfman.ListRequest{
+		ProgramType:        bpfmanHelpers.Xdp.Uint32(),
+		BpfmanProgramsOnly: proto.Bool(true),
+	},
+	&gobpfman.GetRequest{Id: 6371},
+	&gobpfman.PullBytecodeRequest{
+		Image: &gobpfman.BytecodeImage{
+			Url:             "quay.io/bpfman-bytecode/go-xdp-counter:latest",
+			ImagePullPolicy: int32(bytecode.PullIfNotPresent),
+		},
+	},
+}
+
+type schema map[string]interface{}
+
+func main() {
+	var outDir string
+
+	flag.StringVar(&outDir, "out", ".", "Directory to write the schemas and examples to.")
+	flag.Parse()
+
+	if err := generate(outDir); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate writes <Name>.schema.json and <Name>.example.json for each of the
+// requests to outDir.
+func generate(outDir string) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	for _, req := range requests {
+		desc := req.ProtoReflect().Descriptor()
+		name := string(desc.Name())
+
+		s, err := messageSchema(desc)
+		if err != nil {
+			return fmt.Errorf("failed to generate %s schema: %v", name, err)
+		}
+		if err := writeJson(filepath.Join(outDir, name+".schema.json"), s); err != nil {
+			return err
+		}
+
+		// protojson output is deliberately unstable, so re-indent it to keep
+		// the published examples reproducible.
+		example, err := protojson.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s example: %v", name, err)
+		}
+		if err := writeJson(filepath.Join(outDir, name+".example.json"), json.RawMessage(example)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// messageSchema builds a standalone schema for a top level message, with all
+// nested messages under $defs.
+func messageSchema(desc protoreflect.MessageDescriptor) (schema, error) {
+	defs := schema{}
+	root, err := objectSchema(desc, defs)
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = schemaDialect
+	root["$id"] = schemaIdBase + string(desc.Name()) + ".schema.json"
+	root["title"] = string(desc.FullName())
+	if len(defs) > 0 {
+		root["$defs"] = defs
+	}
+	return root, nil
+}
+
+func objectSchema(desc protoreflect.MessageDescriptor, defs schema) (schema, error) {
+	properties := schema{}
+	var constraints []schema
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		fs, err := fieldSchema(field, defs)
+		if err != nil {
+			return nil, err
+		}
+		properties[field.JSONName()] = fs
+
+		// protojson also accepts the original proto field name, e.g.
+		// program_type for programType, but not both at once.
+		if field.TextName() != field.JSONName() {
+			properties[field.TextName()] = properties[field.JSONName()]
+			constraints = append(constraints, schema{
+				"not": schema{"required": []string{field.JSONName(), field.TextName()}},
+			})
+		}
+	}
+
+	s := schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	// protojson accepts at most one member of each oneof: either exactly one
+	// member is present or none are.
+	oneofs := desc.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		oneof := oneofs.Get(i)
+		if oneof.IsSynthetic() {
+			continue
+		}
+		var choices []schema
+		members := oneof.Fields()
+		for j := 0; j < members.Len(); j++ {
+			choices = append(choices, fieldPresent(members.Get(j)))
+		}
+		constraints = append(constraints, schema{
+			"oneOf": append(choices, schema{"not": schema{"anyOf": choices}}),
+		})
+	}
+	if len(constraints) > 0 {
+		s["allOf"] = constraints
+	}
+
+	return s, nil
+}
+
+// fieldPresent returns a schema that matches objects containing the field
+// under either of its names.
+func fieldPresent(field protoreflect.FieldDescriptor) schema {
+	if field.TextName() == field.JSONName() {
+		return schema{"required": []string{field.JSONName()}}
+	}
+	return schema{"anyOf": []schema{
+		{"required": []string{field.JSONName()}},
+		{"required": []string{field.TextName()}},
+	}}
+}
+
+func fieldSchema(field protoreflect.FieldDescriptor, defs schema) (schema, error) {
+	if field.IsMap() {
+		value, err := kindSchema(field.MapValue(), defs)
+		if err != nil {
+			return nil, err
+		}
+		return schema{
+			"type":                 "object",
+			"additionalProperties": value,
+		}, nil
+	}
+	if field.IsList() {
+		item, err := kindSchema(field, defs)
+		if err != nil {
+			return nil, err
+		}
+		return schema{
+			"type":  "array",
+			"items": item,
+		}, nil
+	}
+	return kindSchema(field, defs)
+}
+
+// kindSchema returns the schema for a single value of the field's type,
+// following the protojson encoding rules. protojson accepts numbers both as
+// JSON numbers and as strings, and enums by name or by number.
+func kindSchema(field protoreflect.FieldDescriptor, defs schema) (schema, error) {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return schema{"type": "boolean"}, nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return integerSchema(int64(math.MinInt32), int64(math.MaxInt32)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return integerSchema(0, uint64(math.MaxUint32)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// 64 bit integers are encoded as strings but numbers are accepted.
+		return schema{"type": []string{"integer", "string"}, "pattern": integerPattern}, nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		// Strings cover quoted numbers as well as "NaN" and "Infinity".
+		return schema{"type": []string{"number", "string"}}, nil
+	case protoreflect.StringKind:
+		return schema{"type": "string"}, nil
+	case protoreflect.BytesKind:
+		return schema{"type": "string", "contentEncoding": "base64"}, nil
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		enum := make([]interface{}, 0, 2*values.Len())
+		for i := 0; i < values.Len(); i++ {
+			enum = append(enum, string(values.Get(i).Name()), int32(values.Get(i).Number()))
+		}
+		return schema{"enum": enum}, nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := field.Message()
+		name := string(msg.FullName())
+		if _, ok := defs[name]; !ok {
+			// Reserve the name first so recursive messages terminate.
+			defs[name] = schema{}
+			s, err := objectSchema(msg, defs)
+			if err != nil {
+				return nil, err
+			}
+			defs[name] = s
+		}
+		return schema{"$ref": "#/$defs/" + name}, nil
+	default:
+		return nil, fmt.Errorf("field %s has unsupported kind %s", field.FullName(), field.Kind())
+	}
+}
+
+// integerPattern matches integers encoded as JSON strings.
+const integerPattern = `^-?[0-9]+$`
+
+// integerSchema returns the schema for a 32 bit integer. The bounds only
+// apply to numbers, so quoted values are only checked for being integers.
+func integerSchema(min, max interface{}) schema {
+	return schema{
+		"type":    []string{"integer", "string"},
+		"pattern": integerPattern,
+		"minimum": min,
+		"maximum": max,
+	}
+}
+
+func writeJson(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
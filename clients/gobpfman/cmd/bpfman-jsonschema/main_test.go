@@ -0,0 +1,347 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// validator checks a document against a generated schema. It implements only
+// the keywords the generator emits.
+type validator struct {
+	root map[string]interface{}
+}
+
+func (v validator) validate(s map[string]interface{}, doc interface{}, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		def, ok := v.root["$defs"].(map[string]interface{})[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unresolved $ref %s", path, ref)
+		}
+		return v.validate(def, doc, path)
+	}
+
+	if t, ok := s["type"]; ok {
+		var types []interface{}
+		if list, ok := t.([]interface{}); ok {
+			types = list
+		} else {
+			types = []interface{}{t}
+		}
+		matched := false
+		for _, t := range types {
+			if hasType(doc, t.(string)) {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v is not of type %v", path, doc, t)
+		}
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if e == doc {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, doc, enum)
+		}
+	}
+
+	if pattern, ok := s["pattern"].(string); ok {
+		if str, ok := doc.(string); ok && !regexp.MustCompile(pattern).MatchString(str) {
+			return fmt.Errorf("%s: %q does not match %s", path, str, pattern)
+		}
+	}
+
+	if n, ok := doc.(json.Number); ok {
+		value, _ := new(big.Float).SetString(string(n))
+		if min, ok := s["minimum"].(json.Number); ok {
+			if m, _ := new(big.Float).SetString(string(min)); value.Cmp(m) < 0 {
+				return fmt.Errorf("%s: %s is less than %s", path, n, min)
+			}
+		}
+		if max, ok := s["maximum"].(json.Number); ok {
+			if m, _ := new(big.Float).SetString(string(max)); value.Cmp(m) > 0 {
+				return fmt.Errorf("%s: %s is greater than %s", path, n, max)
+			}
+		}
+	}
+
+	if obj, ok := doc.(map[string]interface{}); ok {
+		properties, _ := s["properties"].(map[string]interface{})
+		for key, value := range obj {
+			if p, ok := properties[key].(map[string]interface{}); ok {
+				if err := v.validate(p, value, path+"."+key); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := s["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: unexpected property %q", path, key)
+				}
+			case map[string]interface{}:
+				if err := v.validate(additional, value, path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, r := range required {
+				if _, ok := obj[r.(string)]; !ok {
+					return fmt.Errorf("%s: missing required property %q", path, r)
+				}
+			}
+		}
+	}
+
+	if items, ok := s["items"].(map[string]interface{}); ok {
+		if list, ok := doc.([]interface{}); ok {
+			for i, item := range list {
+				if err := v.validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if allOf, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			if err := v.validate(sub.(map[string]interface{}), doc, path); err != nil {
+				return err
+			}
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		if v.matches(anyOf, doc, path) == 0 {
+			return fmt.Errorf("%s: does not match any of anyOf", path)
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		if n := v.matches(oneOf, doc, path); n != 1 {
+			return fmt.Errorf("%s: matches %d of oneOf, want exactly 1", path, n)
+		}
+	}
+	if not, ok := s["not"].(map[string]interface{}); ok {
+		if v.validate(not, doc, path) == nil {
+			return fmt.Errorf("%s: matches a \"not\" schema", path)
+		}
+	}
+
+	return nil
+}
+
+func (v validator) matches(schemas []interface{}, doc interface{}, path string) int {
+	n := 0
+	for _, sub := range schemas {
+		if v.validate(sub.(map[string]interface{}), doc, path) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func hasType(doc interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := doc.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := doc.([]interface{})
+		return ok
+	case "string":
+		_, ok := doc.(string)
+		return ok
+	case "boolean":
+		_, ok := doc.(bool)
+		return ok
+	case "number":
+		_, ok := doc.(json.Number)
+		return ok
+	case "integer":
+		n, ok := doc.(json.Number)
+		if !ok {
+			return false
+		}
+		_, ok = new(big.Int).SetString(string(n), 10)
+		return ok
+	}
+	return false
+}
+
+func decodeJson(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func readJson(t *testing.T, path string) interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeJson(t, data)
+}
+
+func generateSchemas(t *testing.T) string {
+	t.Helper()
+	outDir := t.TempDir()
+	if err := generate(outDir); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	return outDir
+}
+
+func schemaValidator(t *testing.T, outDir, name string) validator {
+	t.Helper()
+	return validator{root: readJson(t, filepath.Join(outDir, name+".schema.json")).(map[string]interface{})}
+}
+
+func TestExamplesMatchSchemas(t *testing.T) {
+	outDir := generateSchemas(t)
+
+	for _, req := range requests {
+		name := string(req.ProtoReflect().Descriptor().Name())
+		v := schemaValidator(t, outDir, name)
+		example := readJson(t, filepath.Join(outDir, name+".example.json"))
+		if err := v.validate(v.root, example, name); err != nil {
+			t.Errorf("%s example doesn't match its schema: %v", name, err)
+		}
+	}
+}
+
+func TestLoadRequestSchema(t *testing.T) {
+	outDir := generateSchemas(t)
+	v := schemaValidator(t, outDir, "LoadRequest")
+
+	tests := []struct {
+		name  string
+		doc   string
+		valid bool
+	}{
+		{
+			name:  "lowerCamelCase names",
+			doc:   `{"bytecode": {"file": "/tmp/xdp.o"}, "name": "xdp_stats", "programType": 6}`,
+			valid: true,
+		},
+		{
+			name:  "proto names",
+			doc:   `{"bytecode": {"file": "/tmp/xdp.o"}, "name": "xdp_stats", "program_type": 6}`,
+			valid: true,
+		},
+		{
+			name:  "both names for the same field",
+			doc:   `{"name": "xdp_stats", "programType": 6, "program_type": 6}`,
+			valid: false,
+		},
+		{
+			name:  "oneof with no member set",
+			doc:   `{"bytecode": {}, "name": "xdp_stats"}`,
+			valid: true,
+		},
+		{
+			name:  "oneof with two members set",
+			doc:   `{"bytecode": {"file": "/tmp/xdp.o", "image": {"url": "quay.io/bpfman-bytecode/go-xdp-counter:latest"}}}`,
+			valid: false,
+		},
+		{
+			name:  "unknown property",
+			doc:   `{"name": "xdp_stats", "programTyp": 6}`,
+			valid: false,
+		},
+		{
+			name:  "quoted uint32",
+			doc:   `{"name": "xdp_stats", "programType": "6"}`,
+			valid: true,
+		},
+		{
+			name:  "quoted non-integer",
+			doc:   `{"name": "xdp_stats", "programType": "six"}`,
+			valid: false,
+		},
+		{
+			name:  "out of range uint32",
+			doc:   `{"programType": 4294967296}`,
+			valid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		err := v.validate(v.root, decodeJson(t, []byte(tt.doc)), "LoadRequest")
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected document to be valid: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected document to be invalid", tt.name)
+		}
+
+		// The schema should agree with protojson itself.
+		perr := protojson.Unmarshal([]byte(tt.doc), &gobpfman.LoadRequest{})
+		if tt.valid != (perr == nil) {
+			t.Errorf("%s: schema validity %t disagrees with protojson: %v", tt.name, tt.valid, perr)
+		}
+	}
+}
+
+// The bpfman requests have no enums, so use one from descriptor.proto.
+func TestEnumSchema(t *testing.T) {
+	field := (&descriptorpb.FieldDescriptorProto{}).ProtoReflect().Descriptor().Fields().ByName("type")
+	s, err := kindSchema(field, schema{})
+	if err != nil {
+		t.Fatalf("kindSchema failed: %v", err)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := validator{root: decodeJson(t, data).(map[string]interface{})}
+	for _, doc := range []string{`"TYPE_DOUBLE"`, `1`, `"TYPE_SINT64"`, `18`} {
+		if err := v.validate(v.root, decodeJson(t, []byte(doc)), "type"); err != nil {
+			t.Errorf("%s: %v", doc, err)
+		}
+	}
+	for _, doc := range []string{`"TYPE_UNKNOWN"`, `99`} {
+		if err := v.validate(v.root, decodeJson(t, []byte(doc)), "type"); err == nil {
+			t.Errorf("%s: expected an error", doc)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ciliumebpf translates between cilium/ebpf specs and bpfman
+// requests, so code that already describes its programs with an
+// ebpf.CollectionSpec can have bpfman load and attach them instead of
+// loading them directly.
+//
+// The program type and attach point are taken from the libbpf section name
+// of each program (e.g. "xdp", "kprobe/try_to_wake_up",
+// "tracepoint/syscalls/sys_enter_kill"). Anything the section name can't
+// express, such as the interface for XDP and TC programs, is passed in
+// AttachOptions.
+//
+// In the other direction, OpenProgram returns cilium/ebpf handles for a
+// program bpfman has loaded and for its maps.
+package ciliumebpf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	"github.com/cilium/ebpf"
+)
+
+// AttachOptions supplies the attach parameters that can't be derived from a
+// program's section name. Fields that don't apply to a program type are
+// ignored.
+type AttachOptions struct {
+	// Iface, Priority and Direction are used for XDP and TC programs.
+	Iface     string
+	Priority  int32
+	Direction string
+	// FnName overrides the function from the section name for kprobe,
+	// uprobe, fentry and fexit programs.
+	FnName string
+	// Target overrides the library or executable from the section name for
+	// uprobe programs.
+	Target string
+}
+
+// SectionType returns the libbpf section prefix of a program, e.g. "xdp",
+// "tc" or "kretprobe".
+func SectionType(spec *ebpf.ProgramSpec) string {
+	sectionType, _, _ := strings.Cut(strings.TrimPrefix(spec.SectionName, "?"), "/")
+	return sectionType
+}
+
+// FnName returns the function a kprobe, uprobe, fentry or fexit program
+// attaches to.
+func FnName(spec *ebpf.ProgramSpec, opts AttachOptions) string {
+	if opts.FnName != "" {
+		return opts.FnName
+	}
+	switch SectionType(spec) {
+	case "uprobe", "uretprobe":
+		// libbpf uprobe sections are "uprobe/<target>:<function>"
+		if i := strings.LastIndex(spec.AttachTo, ":"); i >= 0 {
+			return spec.AttachTo[i+1:]
+		}
+		return ""
+	}
+	return spec.AttachTo
+}
+
+// Target returns the library or executable a uprobe program attaches to.
+func Target(spec *ebpf.ProgramSpec, opts AttachOptions) string {
+	if opts.Target != "" {
+		return opts.Target
+	}
+	if i := strings.LastIndex(spec.AttachTo, ":"); i >= 0 {
+		return spec.AttachTo[:i]
+	}
+	return ""
+}
+
+// LoadRequest builds the bpfman LoadRequest for one program of a collection.
+// name is the program's key in CollectionSpec.Programs, which bpfman uses to
+// find the function in the bytecode.
+func LoadRequest(name string, spec *ebpf.ProgramSpec, bytecode *gobpfman.BytecodeLocation,
+	opts AttachOptions) (*gobpfman.LoadRequest, error) {
+	req := &gobpfman.LoadRequest{
+		Bytecode: bytecode,
+		Name:     name,
+	}
+
+	sectionType := SectionType(spec)
+	switch sectionType {
+	case "xdp":
+		if opts.Iface == "" {
+			return nil, fmt.Errorf("interface is required for program %s", name)
+		}
+		req.ProgramType = *bpfmanHelpers.Xdp.Uint32()
+		req.Attach = &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_XdpAttachInfo{
+				XdpAttachInfo: &gobpfman.XDPAttachInfo{
+					Priority: opts.Priority,
+					Iface:    opts.Iface,
+				},
+			},
+		}
+	case "tc", "classifier":
+		if opts.Iface == "" {
+			return nil, fmt.Errorf("interface is required for program %s", name)
+		}
+		req.ProgramType = *bpfmanHelpers.Tc.Uint32()
+		req.Attach = &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_TcAttachInfo{
+				TcAttachInfo: &gobpfman.TCAttachInfo{
+					Priority:  opts.Priority,
+					Iface:     opts.Iface,
+					Direction: opts.Direction,
+				},
+			},
+		}
+	case "tracepoint", "tp":
+		req.ProgramType = *bpfmanHelpers.Tracepoint.Uint32()
+		req.Attach = &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_TracepointAttachInfo{
+				TracepointAttachInfo: &gobpfman.TracepointAttachInfo{
+					Tracepoint: spec.AttachTo,
+				},
+			},
+		}
+	case "kprobe", "kretprobe":
+		req.ProgramType = *bpfmanHelpers.Kprobe.Uint32()
+		req.Attach = &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_KprobeAttachInfo{
+				KprobeAttachInfo: &gobpfman.KprobeAttachInfo{
+					FnName:   FnName(spec, opts),
+					Retprobe: sectionType == "kretprobe",
+				},
+			},
+		}
+	case "uprobe", "uretprobe":
+		fnName := FnName(spec, opts)
+		req.ProgramType = *bpfmanHelpers.Kprobe.Uint32()
+		req.Attach = &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_UprobeAttachInfo{
+				UprobeAttachInfo: &gobpfman.UprobeAttachInfo{
+					FnName:   &fnName,
+					Target:   Target(spec, opts),
+					Retprobe: sectionType == "uretprobe",
+				},
+			},
+		}
+	case "fentry":
+		req.ProgramType = *bpfmanHelpers.Tracing.Uint32()
+		req.Attach = &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_FentryAttachInfo{
+				FentryAttachInfo: &gobpfman.FentryAttachInfo{
+					FnName: FnName(spec, opts),
+				},
+			},
+		}
+	case "fexit":
+		req.ProgramType = *bpfmanHelpers.Tracing.Uint32()
+		req.Attach = &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_FexitAttachInfo{
+				FexitAttachInfo: &gobpfman.FexitAttachInfo{
+					FnName: FnName(spec, opts),
+				},
+			},
+		}
+	default:
+		return nil, fmt.Errorf("section type %q of program %s is not supported by bpfman", sectionType, name)
+	}
+
+	return req, nil
+}
+
+// LoadRequests builds LoadRequests for every program in a collection, keyed
+// by program name. The same AttachOptions are used for all programs.
+func LoadRequests(spec *ebpf.CollectionSpec, bytecode *gobpfman.BytecodeLocation,
+	opts AttachOptions) (map[string]*gobpfman.LoadRequest, error) {
+	reqs := make(map[string]*gobpfman.LoadRequest, len(spec.Programs))
+	for name, progSpec := range spec.Programs {
+		req, err := LoadRequest(name, progSpec, bytecode, opts)
+		if err != nil {
+			return nil, err
+		}
+		reqs[name] = req
+	}
+	return reqs, nil
+}
+
+// Collection is the bpfman-managed counterpart of an ebpf.Collection: the
+// programs are owned by bpfman and the maps are opened from bpfman's pins.
+type Collection struct {
+	// Programs holds the LoadResponse of each program, keyed by name.
+	Programs map[string]*gobpfman.LoadResponse
+	// Maps holds the collection's maps, keyed by name.
+	Maps map[string]*ebpf.Map
+
+	client gobpfman.Unloader
+	// responses are in load order; the first program owns the maps.
+	responses []*gobpfman.LoadResponse
+}
+
+// LoadCollection asks bpfman to load every program in spec and opens the
+// resulting maps, giving the caller the same map handles an
+// ebpf.NewCollection would. Programs are loaded in name order with
+// gobpfman.LoadMapOwnerAndUsers: the first one owns the maps and the others
+// share them via MapOwnerId. If any load fails the programs loaded so far
+// are unloaded.
+func LoadCollection(ctx context.Context, c gobpfman.LoadUnloader, spec *ebpf.CollectionSpec,
+	bytecode *gobpfman.BytecodeLocation, opts AttachOptions) (*Collection, error) {
+	reqs, err := LoadRequests(spec, bytecode, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("collection has no programs")
+	}
+
+	names := make([]string, 0, len(reqs))
+	for name := range reqs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	users := make([]*gobpfman.LoadRequest, 0, len(names)-1)
+	for _, name := range names[1:] {
+		users = append(users, reqs[name])
+	}
+	responses, err := gobpfman.LoadMapOwnerAndUsers(ctx, c, reqs[names[0]], users...)
+	if err != nil {
+		return nil, err
+	}
+
+	coll := &Collection{
+		Programs:  make(map[string]*gobpfman.LoadResponse, len(responses)),
+		Maps:      make(map[string]*ebpf.Map, len(spec.Maps)),
+		client:    c,
+		responses: responses,
+	}
+	for i, name := range names {
+		coll.Programs[name] = responses[i]
+	}
+
+	coll.Maps, err = openPinnedMaps(responses[0].GetInfo().GetMapPinPath(), spec.Maps)
+	if err != nil {
+		if closeErr := coll.Close(ctx); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+		return nil, err
+	}
+
+	return coll, nil
+}
+
+// Close closes the map handles and unloads the programs from bpfman, map
+// users first. All programs are attempted; the first error is returned.
+func (coll *Collection) Close(ctx context.Context) error {
+	for _, m := range coll.Maps {
+		m.Close()
+	}
+	coll.Maps = map[string]*ebpf.Map{}
+
+	err := gobpfman.UnloadMapOwnerAndUsers(ctx, coll.client, coll.responses)
+	coll.Programs = map[string]*gobpfman.LoadResponse{}
+	coll.responses = nil
+
+	return err
+}
+
+// OpenProgram is the reverse of LoadCollection: it returns cilium/ebpf
+// handles for a program bpfman has already loaded, and for the maps pinned
+// in its map pin path, keyed by name. Only handles can be returned, not a
+// ProgramSpec, because bpfman doesn't report the program's instructions.
+func OpenProgram(ctx context.Context, c gobpfman.Getter, id uint32) (*ebpf.Program, map[string]*ebpf.Map, error) {
+	res, err := c.Get(ctx, &gobpfman.GetRequest{Id: id})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get program %d: %v", id, err)
+	}
+	mapPinPath := res.GetInfo().GetMapPinPath()
+	if mapPinPath == "" {
+		return nil, nil, fmt.Errorf("program %d is not managed by bpfman", id)
+	}
+
+	prog, err := ebpf.NewProgramFromID(ebpf.ProgramID(id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open program %d: %v", id, err)
+	}
+
+	entries, err := os.ReadDir(mapPinPath)
+	if err != nil {
+		prog.Close()
+		return nil, nil, fmt.Errorf("failed to read map pin path %s: %v", mapPinPath, err)
+	}
+	names := make(map[string]*ebpf.MapSpec, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names[entry.Name()] = nil
+		}
+	}
+	maps, err := openPinnedMaps(mapPinPath, names)
+	if err != nil {
+		prog.Close()
+		return nil, nil, err
+	}
+
+	return prog, maps, nil
+}
+
+// openPinnedMaps opens the maps named in specs from mapPinPath. Sections
+// such as .rodata and .bss are not pinned by bpfman and are skipped. On
+// error the maps opened so far are closed.
+func openPinnedMaps(mapPinPath string, specs map[string]*ebpf.MapSpec) (map[string]*ebpf.Map, error) {
+	maps := make(map[string]*ebpf.Map, len(specs))
+	for name := range specs {
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		m, err := ebpf.LoadPinnedMap(filepath.Join(mapPinPath, name), nil)
+		if err != nil {
+			for _, m := range maps {
+				m.Close()
+			}
+			return nil, fmt.Errorf("failed to open map %s: %v", name, err)
+		}
+		maps[name] = m
+	}
+	return maps, nil
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ciliumebpf
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	"github.com/cilium/ebpf"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeClient records Load and Unload calls and hands out sequential kernel
+// IDs starting at 100. Loading the program named failName fails.
+type fakeClient struct {
+	failName string
+	nextId   uint32
+	loads    []*gobpfman.LoadRequest
+	unloads  []uint32
+}
+
+func (f *fakeClient) Load(ctx context.Context, in *gobpfman.LoadRequest, opts ...grpc.CallOption) (*gobpfman.LoadResponse, error) {
+	f.loads = append(f.loads, proto.Clone(in).(*gobpfman.LoadRequest))
+	if in.GetName() == f.failName {
+		return nil, fmt.Errorf("load of %s failed", in.GetName())
+	}
+	f.nextId++
+	return &gobpfman.LoadResponse{
+		Info:       &gobpfman.ProgramInfo{Name: in.GetName(), MapPinPath: "/run/bpfman/fs/maps/100"},
+		KernelInfo: &gobpfman.KernelProgramInfo{Id: 99 + f.nextId},
+	}, nil
+}
+
+func (f *fakeClient) Unload(ctx context.Context, in *gobpfman.UnloadRequest, opts ...grpc.CallOption) (*gobpfman.UnloadResponse, error) {
+	f.unloads = append(f.unloads, in.GetId())
+	return &gobpfman.UnloadResponse{}, nil
+}
+
+// testSpec returns a collection without maps, so LoadCollection doesn't need
+// any pins to exist.
+func testSpec() *ebpf.CollectionSpec {
+	return &ebpf.CollectionSpec{
+		Programs: map[string]*ebpf.ProgramSpec{
+			"xdp_b":    {SectionName: "xdp"},
+			"xdp_a":    {SectionName: "xdp"},
+			"kprobe_c": {SectionName: "kprobe/try_to_wake_up", AttachTo: "try_to_wake_up"},
+		},
+	}
+}
+
+var testBytecode = &gobpfman.BytecodeLocation{
+	Location: &gobpfman.BytecodeLocation_File{File: "/tmp/test.o"},
+}
+
+func TestLoadRequest(t *testing.T) {
+	opts := AttachOptions{Iface: "eth0", Priority: 50, Direction: "ingress"}
+
+	tests := []struct {
+		spec        *ebpf.ProgramSpec
+		programType uint32
+		attach      *gobpfman.AttachInfo
+	}{
+		{
+			spec:        &ebpf.ProgramSpec{SectionName: "xdp"},
+			programType: *bpfmanHelpers.Xdp.Uint32(),
+			attach: &gobpfman.AttachInfo{Info: &gobpfman.AttachInfo_XdpAttachInfo{
+				XdpAttachInfo: &gobpfman.XDPAttachInfo{Priority: 50, Iface: "eth0"},
+			}},
+		},
+		{
+			spec:        &ebpf.ProgramSpec{SectionName: "classifier/chain_act"},
+			programType: *bpfmanHelpers.Tc.Uint32(),
+			attach: &gobpfman.AttachInfo{Info: &gobpfman.AttachInfo_TcAttachInfo{
+				TcAttachInfo: &gobpfman.TCAttachInfo{Priority: 50, Iface: "eth0", Direction: "ingress"},
+			}},
+		},
+		{
+			spec:        &ebpf.ProgramSpec{SectionName: "tracepoint/syscalls/sys_enter_kill", AttachTo: "syscalls/sys_enter_kill"},
+			programType: *bpfmanHelpers.Tracepoint.Uint32(),
+			attach: &gobpfman.AttachInfo{Info: &gobpfman.AttachInfo_TracepointAttachInfo{
+				TracepointAttachInfo: &gobpfman.TracepointAttachInfo{Tracepoint: "syscalls/sys_enter_kill"},
+			}},
+		},
+		{
+			spec:        &ebpf.ProgramSpec{SectionName: "kretprobe/try_to_wake_up", AttachTo: "try_to_wake_up"},
+			programType: *bpfmanHelpers.Kprobe.Uint32(),
+			attach: &gobpfman.AttachInfo{Info: &gobpfman.AttachInfo_KprobeAttachInfo{
+				KprobeAttachInfo: &gobpfman.KprobeAttachInfo{FnName: "try_to_wake_up", Retprobe: true},
+			}},
+		},
+		{
+			spec:        &ebpf.ProgramSpec{SectionName: "uprobe/libc:malloc", AttachTo: "libc:malloc"},
+			programType: *bpfmanHelpers.Kprobe.Uint32(),
+			attach: &gobpfman.AttachInfo{Info: &gobpfman.AttachInfo_UprobeAttachInfo{
+				UprobeAttachInfo: &gobpfman.UprobeAttachInfo{FnName: proto.String("malloc"), Target: "libc"},
+			}},
+		},
+		{
+			spec:        &ebpf.ProgramSpec{SectionName: "fexit/do_unlinkat", AttachTo: "do_unlinkat"},
+			programType: *bpfmanHelpers.Tracing.Uint32(),
+			attach: &gobpfman.AttachInfo{Info: &gobpfman.AttachInfo_FexitAttachInfo{
+				FexitAttachInfo: &gobpfman.FexitAttachInfo{FnName: "do_unlinkat"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		req, err := LoadRequest("prog", tt.spec, testBytecode, opts)
+		if err != nil {
+			t.Errorf("%s: LoadRequest failed: %v", tt.spec.SectionName, err)
+			continue
+		}
+		if req.GetProgramType() != tt.programType {
+			t.Errorf("%s: program type %d, want %d", tt.spec.SectionName, req.GetProgramType(), tt.programType)
+		}
+		if !proto.Equal(req.GetAttach(), tt.attach) {
+			t.Errorf("%s: attach info %v, want %v", tt.spec.SectionName, req.GetAttach(), tt.attach)
+		}
+	}
+
+	if _, err := LoadRequest("prog", &ebpf.ProgramSpec{SectionName: "xdp"}, testBytecode, AttachOptions{}); err == nil {
+		t.Errorf("expected an error for an XDP program without an interface")
+	}
+	if _, err := LoadRequest("prog", &ebpf.ProgramSpec{SectionName: "lsm/file_open"}, testBytecode, opts); err == nil {
+		t.Errorf("expected an error for an unsupported section type")
+	}
+}
+
+func TestLoadCollection(t *testing.T) {
+	ctx := context.Background()
+	c := &fakeClient{}
+
+	coll, err := LoadCollection(ctx, c, testSpec(), testBytecode, AttachOptions{Iface: "eth0"})
+	if err != nil {
+		t.Fatalf("LoadCollection failed: %v", err)
+	}
+
+	// Programs are loaded in name order and all share the first one's maps.
+	var names []string
+	for i, req := range c.loads {
+		names = append(names, req.GetName())
+		if i == 0 && req.MapOwnerId != nil {
+			t.Errorf("map owner %s has MapOwnerId %d", req.GetName(), req.GetMapOwnerId())
+		}
+		if i > 0 && req.GetMapOwnerId() != 100 {
+			t.Errorf("program %s has MapOwnerId %v, want 100", req.GetName(), req.MapOwnerId)
+		}
+	}
+	if want := []string{"kprobe_c", "xdp_a", "xdp_b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("load order %v, want %v", names, want)
+	}
+	if len(coll.Programs) != 3 {
+		t.Errorf("collection has %d programs, want 3", len(coll.Programs))
+	}
+
+	// Map users are unloaded before the map owner.
+	if err := coll.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if want := []uint32{102, 101, 100}; !reflect.DeepEqual(c.unloads, want) {
+		t.Errorf("unload order %v, want %v", c.unloads, want)
+	}
+	if len(coll.Programs) != 0 {
+		t.Errorf("collection still has %d programs after Close", len(coll.Programs))
+	}
+}
+
+func TestLoadCollectionRollback(t *testing.T) {
+	ctx := context.Background()
+	c := &fakeClient{failName: "xdp_b"}
+
+	if _, err := LoadCollection(ctx, c, testSpec(), testBytecode, AttachOptions{Iface: "eth0"}); err == nil {
+		t.Fatalf("expected LoadCollection to fail")
+	}
+	if want := []uint32{101, 100}; !reflect.DeepEqual(c.unloads, want) {
+		t.Errorf("unloaded %v after a failed load, want %v", c.unloads, want)
+	}
+}
+
+// fakeGetter returns info for every Get, or err if set.
+type fakeGetter struct {
+	info *gobpfman.ProgramInfo
+	err  error
+}
+
+func (f *fakeGetter) Get(ctx context.Context, in *gobpfman.GetRequest, opts ...grpc.CallOption) (*gobpfman.GetResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &gobpfman.GetResponse{Info: f.info}, nil
+}
+
+func TestOpenProgramErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		getter *fakeGetter
+		want   string
+	}{
+		{name: "get fails", getter: &fakeGetter{err: fmt.Errorf("not found")}, want: "failed to get program 6371"},
+		{name: "not managed by bpfman", getter: &fakeGetter{}, want: "not managed by bpfman"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := OpenProgram(context.Background(), tt.getter, 6371)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Redacted replaces secret values in audit log records.
+const Redacted = "REDACTED"
+
+// secretFields are the field names whose values are never logged. Matching
+// is by exact name, so a new credential field must be added here; today the
+// only ones are BytecodeImage.username and BytecodeImage.password.
+var secretFields = map[protoreflect.Name]bool{
+	"username": true,
+	"password": true,
+}
+
+// AuditLogger logs every Bpfman RPC made through its interceptor as one
+// structured record with the method, program id, duration and outcome.
+// Secrets in requests are redacted. Logging can be switched on and off at
+// runtime with SetEnabled, e.g. from a signal handler or a debug endpoint.
+type AuditLogger struct {
+	logger  *slog.Logger
+	enabled atomic.Bool
+}
+
+// NewAuditLogger returns an enabled AuditLogger writing to logger, or to
+// slog.Default() if logger is nil.
+func NewAuditLogger(logger *slog.Logger) *AuditLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	a := &AuditLogger{logger: logger}
+	a.enabled.Store(true)
+	return a
+}
+
+// SetEnabled turns audit logging on or off. It is safe to call while RPCs
+// are in flight.
+func (a *AuditLogger) SetEnabled(enabled bool) {
+	a.enabled.Store(enabled)
+}
+
+// Enabled reports whether audit logging is on.
+func (a *AuditLogger) Enabled() bool {
+	return a.enabled.Load()
+}
+
+// UnaryClientInterceptor returns the interceptor that writes the audit
+// records:
+//
+//	audit := gobpfman.NewAuditLogger(nil)
+//	conn, err := grpc.NewClient(addr,
+//		grpc.WithUnaryInterceptor(audit.UnaryClientInterceptor()))
+//
+// Successful calls are logged at Info and failed calls at Warn. The redacted
+// request is included at Debug.
+func (a *AuditLogger) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !a.Enabled() {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		duration := time.Since(start)
+
+		attrs := []slog.Attr{
+			slog.String("method", method),
+			slog.Duration("duration", duration),
+			slog.String("code", status.Code(err).String()),
+		}
+		if id, ok := auditProgramId(req, reply, err); ok {
+			attrs = append(attrs, slog.Uint64("program_id", uint64(id)))
+		}
+		if a.logger.Enabled(ctx, slog.LevelDebug) {
+			if msg, ok := req.(proto.Message); ok {
+				attrs = append(attrs, slog.String("request", protojson.Format(RedactSecrets(msg))))
+			}
+		}
+
+		level := slog.LevelInfo
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+		}
+		a.logger.LogAttrs(ctx, level, "bpfman rpc", attrs...)
+
+		return err
+	}
+}
+
+// auditProgramId returns the kernel program id an RPC acted on, taken from
+// the request or, for a successful Load, from the response.
+func auditProgramId(req, reply interface{}, err error) (uint32, bool) {
+	switch r := req.(type) {
+	case *UnloadRequest:
+		return r.GetId(), true
+	case *GetRequest:
+		return r.GetId(), true
+	case *LoadRequest:
+		if res, ok := reply.(*LoadResponse); ok && err == nil && res.GetKernelInfo() != nil {
+			return res.GetKernelInfo().GetId(), true
+		}
+	}
+	return 0, false
+}
+
+// RedactSecrets returns a copy of msg with every string field named in
+// secretFields, at any depth, replaced by Redacted. msg itself is not
+// modified.
+func RedactSecrets(msg proto.Message) proto.Message {
+	clone := proto.Clone(msg)
+	redactMessage(clone.ProtoReflect())
+	return clone
+}
+
+func redactMessage(m protoreflect.Message) {
+	var secrets []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case secretFields[fd.Name()] && fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap():
+			secrets = append(secrets, fd)
+		case fd.IsMap():
+			if fd.MapValue().Kind() == protoreflect.MessageKind {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					redactMessage(mv.Message())
+					return true
+				})
+			}
+		case fd.IsList():
+			if fd.Kind() == protoreflect.MessageKind {
+				list := v.List()
+				for i := 0; i < list.Len(); i++ {
+					redactMessage(list.Get(i).Message())
+				}
+			}
+		case fd.Kind() == protoreflect.MessageKind:
+			redactMessage(v.Message())
+		}
+		return true
+	})
+
+	// Set after Range, which doesn't allow setting fields while iterating.
+	for _, fd := range secrets {
+		m.Set(fd, protoreflect.ValueOfString(Redacted))
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func testImage() *BytecodeImage {
+	return &BytecodeImage{
+		Url:      "quay.io/bpfman-bytecode/go-xdp-counter:latest",
+		Username: proto.String("bpfman"),
+		Password: proto.String("hunter2"),
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   proto.Message
+		image func(proto.Message) *BytecodeImage
+	}{
+		{
+			name: "LoadRequest",
+			msg: &LoadRequest{
+				Bytecode: &BytecodeLocation{Location: &BytecodeLocation_Image{Image: testImage()}},
+				Name:     "xdp_stats",
+			},
+			image: func(m proto.Message) *BytecodeImage { return m.(*LoadRequest).GetBytecode().GetImage() },
+		},
+		{
+			name:  "PullBytecodeRequest",
+			msg:   &PullBytecodeRequest{Image: testImage()},
+			image: func(m proto.Message) *BytecodeImage { return m.(*PullBytecodeRequest).GetImage() },
+		},
+	}
+
+	for _, tt := range tests {
+		orig := proto.Clone(tt.msg)
+		redacted := tt.image(RedactSecrets(tt.msg))
+
+		if redacted.GetPassword() != Redacted || redacted.GetUsername() != Redacted {
+			t.Errorf("%s: credentials %q/%q not redacted", tt.name, redacted.GetUsername(), redacted.GetPassword())
+		}
+		if redacted.GetUrl() != testImage().GetUrl() {
+			t.Errorf("%s: url changed to %q", tt.name, redacted.GetUrl())
+		}
+		if !proto.Equal(tt.msg, orig) {
+			t.Errorf("%s: caller's request was modified: %v", tt.name, tt.msg)
+		}
+	}
+
+	// Unset secrets stay unset rather than becoming Redacted.
+	redacted := RedactSecrets(&PullBytecodeRequest{Image: &BytecodeImage{Url: "quay.io/x"}}).(*PullBytecodeRequest)
+	if redacted.GetImage().Password != nil || redacted.GetImage().Username != nil {
+		t.Errorf("unset credentials were set: %v", redacted)
+	}
+}
+
+// auditRecord runs one call through an AuditLogger's interceptor, with
+// invoker standing in for the server, and returns the decoded log record.
+func auditRecord(t *testing.T, level slog.Level, method string, req, reply interface{}, invoker grpc.UnaryInvoker) (map[string]interface{}, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level}))
+	interceptor := NewAuditLogger(logger).UnaryClientInterceptor()
+
+	err := interceptor(context.Background(), method, req, reply, nil, invoker)
+
+	var record map[string]interface{}
+	if jerr := json.Unmarshal(buf.Bytes(), &record); jerr != nil {
+		t.Fatalf("%s: failed to decode log record %q: %v", method, buf.String(), jerr)
+	}
+	return record, err
+}
+
+func TestAuditUnaryClientInterceptor(t *testing.T) {
+	loadOk := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		reply.(*LoadResponse).KernelInfo = &KernelProgramInfo{Id: 6371}
+		return nil
+	}
+	notFound := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.NotFound, "no program with id 42")
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		req       interface{}
+		reply     interface{}
+		invoker   grpc.UnaryInvoker
+		level     string
+		code      string
+		programId interface{}
+		errMsg    interface{}
+	}{
+		{
+			name:      "load succeeds",
+			method:    "/bpfman.v1.Bpfman/Load",
+			req:       &LoadRequest{Name: "xdp_stats"},
+			reply:     &LoadResponse{},
+			invoker:   loadOk,
+			level:     "INFO",
+			code:      "OK",
+			programId: float64(6371),
+		},
+		{
+			name:    "load fails",
+			method:  "/bpfman.v1.Bpfman/Load",
+			req:     &LoadRequest{Name: "xdp_stats"},
+			reply:   &LoadResponse{},
+			invoker: notFound,
+			level:   "WARN",
+			code:    "NotFound",
+			errMsg:  "no program with id 42",
+		},
+		{
+			name:      "unload fails",
+			method:    "/bpfman.v1.Bpfman/Unload",
+			req:       &UnloadRequest{Id: 42},
+			reply:     &UnloadResponse{},
+			invoker:   notFound,
+			level:     "WARN",
+			code:      "NotFound",
+			programId: float64(42),
+			errMsg:    "no program with id 42",
+		},
+	}
+
+	for _, tt := range tests {
+		record, err := auditRecord(t, slog.LevelInfo, tt.method, tt.req, tt.reply, tt.invoker)
+		if code := status.Code(err).String(); code != tt.code {
+			t.Errorf("%s: interceptor returned code %s, want %s", tt.name, code, tt.code)
+		}
+
+		if record["level"] != tt.level {
+			t.Errorf("%s: level %v, want %s", tt.name, record["level"], tt.level)
+		}
+		if record["method"] != tt.method {
+			t.Errorf("%s: method %v, want %s", tt.name, record["method"], tt.method)
+		}
+		if record["code"] != tt.code {
+			t.Errorf("%s: code %v, want %s", tt.name, record["code"], tt.code)
+		}
+		if record["program_id"] != tt.programId {
+			t.Errorf("%s: program_id %v, want %v", tt.name, record["program_id"], tt.programId)
+		}
+		if record["error"] != tt.errMsg {
+			t.Errorf("%s: error %v, want %v", tt.name, record["error"], tt.errMsg)
+		}
+		if _, ok := record["request"]; ok {
+			t.Errorf("%s: request logged at Info", tt.name)
+		}
+	}
+}
+
+func TestAuditLogsRedactedRequestAtDebug(t *testing.T) {
+	req := &PullBytecodeRequest{Image: testImage()}
+	ok := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+
+	record, err := auditRecord(t, slog.LevelDebug, "/bpfman.v1.Bpfman/PullBytecode", req, &PullBytecodeResponse{}, ok)
+	if err != nil {
+		t.Fatalf("interceptor failed: %v", err)
+	}
+	logged, _ := record["request"].(string)
+	if !strings.Contains(logged, Redacted) || strings.Contains(logged, "hunter2") {
+		t.Errorf("request not redacted: %q", logged)
+	}
+	if req.GetImage().GetPassword() != "hunter2" {
+		t.Errorf("caller's request was modified: %v", req)
+	}
+}
+
+func TestAuditDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	audit := NewAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	audit.SetEnabled(false)
+
+	called := false
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		called = true
+		return nil
+	}
+	if err := audit.UnaryClientInterceptor()(context.Background(), "/bpfman.v1.Bpfman/List",
+		&ListRequest{}, &ListResponse{}, nil, invoker); err != nil {
+		t.Fatalf("interceptor failed: %v", err)
+	}
+	if !called {
+		t.Errorf("invoker was not called")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("disabled logger wrote %q", buf.String())
+	}
+}
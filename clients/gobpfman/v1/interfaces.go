@@ -0,0 +1,73 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// The interfaces below split BpfmanClient into single-capability pieces so
+// callers can depend on (and tests can fake) only the RPCs they use. The
+// generated BpfmanClient satisfies all of them.
+
+// Loader loads and attaches eBPF programs.
+type Loader interface {
+	Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*LoadResponse, error)
+}
+
+// Unloader unloads eBPF programs previously loaded by bpfman.
+type Unloader interface {
+	Unload(ctx context.Context, in *UnloadRequest, opts ...grpc.CallOption) (*UnloadResponse, error)
+}
+
+// Lister lists eBPF programs.
+type Lister interface {
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+}
+
+// Getter retrieves a single eBPF program by kernel ID.
+type Getter interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+}
+
+// BytecodePuller pulls bytecode images ahead of a load.
+type BytecodePuller interface {
+	PullBytecode(ctx context.Context, in *PullBytecodeRequest, opts ...grpc.CallOption) (*PullBytecodeResponse, error)
+}
+
+// LoadUnloader is implemented by clients that manage the full program
+// lifecycle.
+type LoadUnloader interface {
+	Loader
+	Unloader
+}
+
+// Reader is implemented by clients that only query program state.
+type Reader interface {
+	Lister
+	Getter
+}
+
+var (
+	_ Loader         = BpfmanClient(nil)
+	_ Unloader       = BpfmanClient(nil)
+	_ Lister         = BpfmanClient(nil)
+	_ Getter         = BpfmanClient(nil)
+	_ BytecodePuller = BpfmanClient(nil)
+)
@@ -0,0 +1,82 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// LoadMapOwnerAndUsers loads a set of programs that share maps through bpfman.
+// The owner is loaded first so that it creates and pins the maps, then each
+// user is loaded with its MapOwnerId set to the owner's kernel program id.
+// The programs may be of different types as long as the shared maps have the
+// same name and definition in each program's bytecode.
+//
+// The returned responses are in load order, owner first. If any load fails,
+// the programs loaded so far are unloaded before the error is returned.
+func LoadMapOwnerAndUsers(ctx context.Context, c LoadUnloader,
+	owner *LoadRequest, users ...*LoadRequest) ([]*LoadResponse, error) {
+	var responses []*LoadResponse
+
+	res, err := c.Load(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load map owner %s: %v", owner.GetName(), err)
+	}
+	if res.GetKernelInfo() == nil {
+		return nil, fmt.Errorf("kernelInfo not returned in LoadResponse for map owner %s", owner.GetName())
+	}
+	responses = append(responses, res)
+
+	mapOwnerId := res.GetKernelInfo().GetId()
+	for _, user := range users {
+		user.MapOwnerId = &mapOwnerId
+
+		res, err = c.Load(ctx, user)
+		if err == nil && res.GetKernelInfo() == nil {
+			err = fmt.Errorf("kernelInfo not returned in LoadResponse")
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to load map user %s: %v", user.GetName(), err)
+			if unloadErr := UnloadMapOwnerAndUsers(ctx, c, responses); unloadErr != nil {
+				err = errors.Join(err, unloadErr)
+			}
+			return nil, err
+		}
+		responses = append(responses, res)
+	}
+
+	return responses, nil
+}
+
+// UnloadMapOwnerAndUsers unloads programs previously returned by
+// LoadMapOwnerAndUsers. Users are unloaded before the owner, in reverse load
+// order, so the shared maps are only released once nothing references them.
+// All programs are attempted; the first error encountered is returned.
+func UnloadMapOwnerAndUsers(ctx context.Context, c Unloader, responses []*LoadResponse) error {
+	var firstErr error
+
+	for i := len(responses) - 1; i >= 0; i-- {
+		id := responses[i].GetKernelInfo().GetId()
+		if _, err := c.Unload(ctx, &UnloadRequest{Id: id}); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to unload program %s (%d): %v", responses[i].GetInfo().GetName(), id, err)
+		}
+	}
+
+	return firstErr
+}
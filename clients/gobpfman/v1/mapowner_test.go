@@ -0,0 +1,119 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeLoadUnloader hands out sequential kernel IDs starting at 100 and
+// records the requests it gets. Loading the program named failName fails.
+type fakeLoadUnloader struct {
+	failName string
+	nextId   uint32
+	loads    []*LoadRequest
+	unloads  []uint32
+}
+
+var _ LoadUnloader = &fakeLoadUnloader{}
+
+func (f *fakeLoadUnloader) Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*LoadResponse, error) {
+	f.loads = append(f.loads, proto.Clone(in).(*LoadRequest))
+	if in.GetName() == f.failName {
+		return nil, fmt.Errorf("load of %s failed", in.GetName())
+	}
+	id := 100 + f.nextId
+	f.nextId++
+	return &LoadResponse{
+		Info:       &ProgramInfo{Name: in.GetName()},
+		KernelInfo: &KernelProgramInfo{Id: id},
+	}, nil
+}
+
+func (f *fakeLoadUnloader) Unload(ctx context.Context, in *UnloadRequest, opts ...grpc.CallOption) (*UnloadResponse, error) {
+	f.unloads = append(f.unloads, in.GetId())
+	return &UnloadResponse{}, nil
+}
+
+func mapOwnerRequests() (*LoadRequest, []*LoadRequest) {
+	return &LoadRequest{Name: "owner"},
+		[]*LoadRequest{{Name: "user1"}, {Name: "user2"}}
+}
+
+func TestLoadMapOwnerAndUsers(t *testing.T) {
+	ctx := context.Background()
+	c := &fakeLoadUnloader{}
+	owner, users := mapOwnerRequests()
+
+	responses, err := LoadMapOwnerAndUsers(ctx, c, owner, users...)
+	if err != nil {
+		t.Fatalf("LoadMapOwnerAndUsers failed: %v", err)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3", len(responses))
+	}
+
+	if c.loads[0].MapOwnerId != nil {
+		t.Errorf("owner loaded with MapOwnerId %d", c.loads[0].GetMapOwnerId())
+	}
+	for _, req := range c.loads[1:] {
+		if req.MapOwnerId == nil || req.GetMapOwnerId() != 100 {
+			t.Errorf("user %s loaded with MapOwnerId %v, want 100", req.GetName(), req.MapOwnerId)
+		}
+	}
+
+	// Users are unloaded before the owner.
+	if err := UnloadMapOwnerAndUsers(ctx, c, responses); err != nil {
+		t.Fatalf("UnloadMapOwnerAndUsers failed: %v", err)
+	}
+	if want := []uint32{102, 101, 100}; !reflect.DeepEqual(c.unloads, want) {
+		t.Errorf("unload order %v, want %v", c.unloads, want)
+	}
+}
+
+func TestLoadMapOwnerAndUsersRollback(t *testing.T) {
+	tests := []struct {
+		failName string
+		unloads  []uint32
+	}{
+		{failName: "owner", unloads: nil},
+		{failName: "user1", unloads: []uint32{100}},
+		{failName: "user2", unloads: []uint32{101, 100}},
+	}
+
+	for _, tt := range tests {
+		c := &fakeLoadUnloader{failName: tt.failName}
+		owner, users := mapOwnerRequests()
+
+		responses, err := LoadMapOwnerAndUsers(context.Background(), c, owner, users...)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.failName)
+		}
+		if responses != nil {
+			t.Errorf("%s: got %d responses after a failed load", tt.failName, len(responses))
+		}
+		if !reflect.DeepEqual(c.unloads, tt.unloads) {
+			t.Errorf("%s: unloaded %v, want %v", tt.failName, c.unloads, tt.unloads)
+		}
+	}
+}
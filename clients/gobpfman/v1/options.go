@@ -0,0 +1,51 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"google.golang.org/grpc"
+	// Importing gzip registers it, which UseCompressor needs. grpc-go then
+	// advertises it in grpc-accept-encoding for every connection in the
+	// process, but bpfman-rpc never compresses its responses.
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// DefaultMaxRecvMsgSize is the receive limit used by WithLargeResponses. It
+// is large enough for a ListResponse on hosts with several thousand kernel
+// programs, where the grpc-go default of 4MiB is exceeded.
+const DefaultMaxRecvMsgSize = 64 * 1024 * 1024
+
+// WithMaxRecvMsgSize returns a dial option that raises the maximum response
+// size the client will accept, in bytes.
+func WithMaxRecvMsgSize(bytes int) grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(bytes))
+}
+
+// WithLargeResponses returns a dial option for callers that list all
+// programs on busy hosts, such as discovery. It is equivalent to
+// WithMaxRecvMsgSize(DefaultMaxRecvMsgSize).
+func WithLargeResponses() grpc.DialOption {
+	return WithMaxRecvMsgSize(DefaultMaxRecvMsgSize)
+}
+
+// WithGzipRequests returns a dial option that compresses requests with gzip.
+// bpfman-rpc accepts gzip requests but always sends uncompressed responses,
+// so large responses need WithLargeResponses instead. Older bpfman-rpc
+// versions reject compressed requests with codes.Unimplemented.
+func WithGzipRequests() grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name))
+}
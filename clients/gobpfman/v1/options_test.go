@@ -0,0 +1,148 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// listServer answers List with programs programs, each with a 1KiB name.
+type listServer struct {
+	UnimplementedBpfmanServer
+	programs int
+}
+
+func (s *listServer) List(ctx context.Context, in *ListRequest) (*ListResponse, error) {
+	res := &ListResponse{}
+	for i := 0; i < s.programs; i++ {
+		res.Results = append(res.Results, &ListResponse_ListResult{
+			Info: &ProgramInfo{Name: strings.Repeat("x", 1024)},
+		})
+	}
+	return res, nil
+}
+
+// compressionRecorder is a client stats handler that records the
+// compression of each outgoing request.
+type compressionRecorder struct {
+	mu          sync.Mutex
+	compression []string
+}
+
+func (r *compressionRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if h, ok := s.(*stats.OutHeader); ok {
+		r.mu.Lock()
+		r.compression = append(r.compression, h.Compression)
+		r.mu.Unlock()
+	}
+}
+
+func (r *compressionRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+// dialListServer starts an in-process server and returns a client for it
+// that was dialled with opts.
+func dialListServer(t *testing.T, programs int, opts ...grpc.DialOption) BpfmanClient {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	RegisterBpfmanServer(s, &listServer{programs: programs})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewBpfmanClient(conn)
+}
+
+func TestMaxRecvMsgSize(t *testing.T) {
+	// About 5MiB, over the grpc-go default limit of 4MiB.
+	const programs = 5 * 1024
+
+	tests := []struct {
+		name string
+		opts []grpc.DialOption
+		code codes.Code
+	}{
+		{name: "default", code: codes.ResourceExhausted},
+		{name: "WithLargeResponses", opts: []grpc.DialOption{WithLargeResponses()}, code: codes.OK},
+		{name: "WithMaxRecvMsgSize", opts: []grpc.DialOption{WithMaxRecvMsgSize(8 * 1024 * 1024)}, code: codes.OK},
+		{name: "WithMaxRecvMsgSize too small", opts: []grpc.DialOption{WithMaxRecvMsgSize(1024)}, code: codes.ResourceExhausted},
+	}
+
+	for _, tt := range tests {
+		c := dialListServer(t, programs, tt.opts...)
+		res, err := c.List(context.Background(), &ListRequest{})
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: List returned code %v, want %v: %v", tt.name, code, tt.code, err)
+			continue
+		}
+		if err == nil && len(res.GetResults()) != programs {
+			t.Errorf("%s: List returned %d results, want %d", tt.name, len(res.GetResults()), programs)
+		}
+	}
+}
+
+func TestWithGzipRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []grpc.DialOption
+		want string
+	}{
+		{name: "default", want: ""},
+		{name: "WithGzipRequests", opts: []grpc.DialOption{WithGzipRequests()}, want: "gzip"},
+	}
+
+	for _, tt := range tests {
+		r := &compressionRecorder{}
+		c := dialListServer(t, 1, append(tt.opts, grpc.WithStatsHandler(r))...)
+		if _, err := c.List(context.Background(), &ListRequest{}); err != nil {
+			t.Errorf("%s: List failed: %v", tt.name, err)
+			continue
+		}
+		if len(r.compression) != 1 || r.compression[0] != tt.want {
+			t.Errorf("%s: request compression %q, want [%q]", tt.name, r.compression, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mutatingMethods are the Bpfman RPCs that change the datapath.
+var mutatingMethods = map[string]bool{
+	"/bpfman.v1.Bpfman/Load":   true,
+	"/bpfman.v1.Bpfman/Unload": true,
+}
+
+// readOnlyMethods are the Bpfman RPCs a read-only client may call. Every
+// method in Bpfman_ServiceDesc must be in exactly one of the two sets.
+var readOnlyMethods = map[string]bool{
+	"/bpfman.v1.Bpfman/List":         true,
+	"/bpfman.v1.Bpfman/Get":          true,
+	"/bpfman.v1.Bpfman/PullBytecode": true,
+}
+
+// IsMutatingMethod reports whether the full gRPC method name is a Bpfman RPC
+// that loads, attaches or unloads programs.
+func IsMutatingMethod(method string) bool {
+	return mutatingMethods[method]
+}
+
+// ReadOnlyUnaryClientInterceptor returns an interceptor that rejects Load and
+// Unload calls with codes.PermissionDenied before they are sent, so a client
+// connection can be restricted to discovery and monitoring:
+//
+//	conn, err := grpc.NewClient(addr,
+//		grpc.WithUnaryInterceptor(gobpfman.ReadOnlyUnaryClientInterceptor()))
+//
+// List, Get and PullBytecode are passed through unchanged. Any other method,
+// such as an RPC added in a newer version of the API, is rejected too.
+func ReadOnlyUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !readOnlyMethods[method] {
+			return status.Errorf(codes.PermissionDenied, "%s is not allowed on a read-only client", method)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadOnlyUnaryClientInterceptor(t *testing.T) {
+	tests := []struct {
+		method  string
+		allowed bool
+	}{
+		{method: "/bpfman.v1.Bpfman/Load", allowed: false},
+		{method: "/bpfman.v1.Bpfman/Unload", allowed: false},
+		{method: "/bpfman.v1.Bpfman/List", allowed: true},
+		{method: "/bpfman.v1.Bpfman/Get", allowed: true},
+		{method: "/bpfman.v1.Bpfman/PullBytecode", allowed: true},
+		{method: "/bpfman.v1.Bpfman/NewMethod", allowed: false},
+	}
+
+	interceptor := ReadOnlyUnaryClientInterceptor()
+	for _, tt := range tests {
+		invoked := false
+		invoker := func(ctx context.Context, method string, req, reply interface{},
+			cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			invoked = true
+			return nil
+		}
+
+		err := interceptor(context.Background(), tt.method, nil, nil, nil, invoker)
+		if tt.allowed {
+			if err != nil || !invoked {
+				t.Errorf("%s: expected the call to pass through, got invoked=%t err=%v", tt.method, invoked, err)
+			}
+			continue
+		}
+		if invoked {
+			t.Errorf("%s: invoker was called", tt.method)
+		}
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: got %v, want PermissionDenied", tt.method, err)
+		}
+	}
+}
+
+// Every RPC must be classified, so a new one can't default to allowed
+// without a decision being made.
+func TestEveryMethodIsClassified(t *testing.T) {
+	for _, m := range Bpfman_ServiceDesc.Methods {
+		method := "/" + Bpfman_ServiceDesc.ServiceName + "/" + m.MethodName
+		if mutatingMethods[method] == readOnlyMethods[method] {
+			t.Errorf("%s must be in exactly one of mutatingMethods and readOnlyMethods", method)
+		}
+		if IsMutatingMethod(method) != mutatingMethods[method] {
+			t.Errorf("IsMutatingMethod(%s) = %t", method, IsMutatingMethod(method))
+		}
+	}
+	for _, s := range Bpfman_ServiceDesc.Streams {
+		t.Errorf("streaming method %s is not covered by the unary interceptor", s.StreamName)
+	}
+}
@@ -0,0 +1,70 @@
+// map-pin-path prints where the maps of a program will be pinned once bpfman
+// loads it, so deployment manifests can be written before the program is
+// loaded. With -csi_mount_path it prints the paths as seen inside a pod,
+// plus the CSI volumeAttributes to request them.
+//
+// Examples:
+//
+//	map-pin-path -program xdp_stats -maps xdp_stats_map -map_owner_id 6371
+//	map-pin-path -program go-xdp-counter-example -maps xdp_stats_map -csi_mount_path /run/xdp/maps
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+)
+
+func main() {
+	var (
+		programName string
+		maps        string
+		opts        configMgmt.MapPinPathOptions
+		mapOwnerId  uint
+	)
+
+	flag.StringVar(&programName, "program", "", "Name of the program, or of its *Program CRD with -csi_mount_path. Required.")
+	flag.StringVar(&maps, "maps", "", "Comma separated names of the maps. Required.")
+	flag.UintVar(&mapOwnerId, "map_owner_id", 0,
+		"Kernel ID of the program that owns the maps, if they are shared.")
+	flag.StringVar(&opts.CsiMountPath, "csi_mount_path", "",
+		"mountPath of the bpfman CSI volume in the pod that reads the maps.")
+	flag.Parse()
+
+	if programName == "" {
+		flag.Usage()
+		log.Fatal("\"program\" is required")
+	}
+	if maps == "" {
+		flag.Usage()
+		log.Fatal("\"maps\" is required")
+	}
+	opts.MapOwnerId = uint32(mapOwnerId)
+
+	mapNames := strings.Split(maps, ",")
+	for _, mapName := range mapNames {
+		path, err := configMgmt.PredictMapPinPath(programName, mapName, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(path)
+	}
+
+	if opts.CsiMountPath != "" {
+		attrs := configMgmt.CsiVolumeAttributes(programName, mapNames...)
+		keys := make([]string, 0, len(attrs))
+		for k := range attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Println("volumeAttributes:")
+		for _, k := range keys {
+			fmt.Printf("  %s: %s\n", k, attrs[k])
+		}
+	}
+}
@@ -0,0 +1,73 @@
+//go:build linux
+// +build linux
+
+// map-usage prints the capacity, estimated memory footprint and occupancy of
+// the maps of a program loaded by bpfman, largest first, to help size
+// max_entries.
+//
+// Example:
+//
+//	sudo ./map-usage -id 6371 -top 5
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+)
+
+func main() {
+	var (
+		progId      uint
+		sampleLimit uint
+		topN        int
+	)
+
+	flag.UintVar(&progId, "id", 0, "Kernel ID of the program whose maps to sample. Required.")
+	flag.UintVar(&sampleLimit, "sample_limit", configMgmt.DefaultMapUsageSampleLimit,
+		"Maximum number of keys to walk per map.")
+	flag.IntVar(&topN, "top", 0, "Only print the N largest maps. Defaults to all maps.")
+	flag.Parse()
+
+	if progId == 0 {
+		log.Fatal("\"id\" is required")
+	}
+
+	ctx := context.Background()
+	conn, err := configMgmt.CreateConnection(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	c := gobpfman.NewBpfmanClient(conn)
+	res, err := c.Get(ctx, &gobpfman.GetRequest{Id: uint32(progId)})
+	if err != nil {
+		log.Fatalf("failed to get program %d: %v", progId, err)
+	}
+
+	usages, err := configMgmt.SampleProgramMapUsage(res.GetInfo().GetMapPinPath(), uint32(sampleLimit), topN)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tMAX ENTRIES\tUSED\tMEMORY (BYTES)")
+	for _, u := range usages {
+		used := fmt.Sprintf("%d (%.1f%%)", u.UsedEntries, u.Utilization()*100)
+		switch {
+		case u.Err != nil:
+			used = fmt.Sprintf("unknown: %v", u.Err)
+		case u.Sampled:
+			used = fmt.Sprintf(">=%d", u.UsedEntries)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\n", u.Name, u.Type, u.MaxEntries, used, u.MemoryBytes)
+	}
+	w.Flush()
+}
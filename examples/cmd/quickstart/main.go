@@ -0,0 +1,245 @@
+//go:build linux
+// +build linux
+
+// quickstart checks that bpfman is reachable on the local host, loads the
+// go-xdp-counter bytecode on an interface, waits for its map to be pinned,
+// prints the packet counters for a while and unloads the program again. It
+// exits non-zero if any step fails, so it also serves as a quick end to end
+// check of a bpfman install.
+//
+// Example:
+//
+//	sudo ./quickstart -iface eth0
+//
+// For a Kubernetes cluster, use "make deploy-xdp" in the examples directory
+// instead.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
+	"github.com/bpfman/bpfman/clients/gobpfman/bytecode"
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+	"github.com/cilium/ebpf"
+	"google.golang.org/protobuf/proto"
+)
+
+type Stats struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+const (
+	DefaultImage       = "quay.io/bpfman-bytecode/go-xdp-counter:latest"
+	BpfProgramName     = "xdp_stats"
+	BpfProgramMapIndex = "xdp_stats_map"
+
+	XDP_ACT_OK = 2
+)
+
+type options struct {
+	iface    string
+	image    string
+	priority int
+	duration time.Duration
+	timeout  time.Duration
+}
+
+func main() {
+	var opts options
+
+	flag.StringVar(&opts.iface, "iface", "", "Interface to attach the XDP counter to. Required.")
+	flag.StringVar(&opts.image, "image", DefaultImage, "Bytecode image of the XDP counter.")
+	flag.IntVar(&opts.priority, "priority", 50, "Priority to load the program at.")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "How long to print counters for.")
+	flag.DurationVar(&opts.timeout, "timeout", 60*time.Second,
+		"How long to wait for bpfman to respond and for the program to become ready.\n"+
+			"Pulling the bytecode image on first use is included in this.")
+	flag.Parse()
+
+	if opts.iface == "" {
+		log.Fatal("\"iface\" is required")
+	}
+
+	if err := run(opts); err != nil {
+		log.Fatalf("quickstart failed: %v", err)
+	}
+	log.Printf("quickstart completed successfully\n")
+}
+
+func run(opts options) error {
+	image, err := bytecode.NewImage(opts.image, bytecode.PullIfNotPresent, bytecode.TagPolicyAny)
+	if err != nil {
+		return err
+	}
+
+	// Interrupting cancels ctx, which aborts whichever step is in progress.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := configMgmt.CreateConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	c := gobpfman.NewBpfmanClient(conn)
+
+	// 1. Check bpfman is reachable. The connection is established lazily, so
+	//    make a cheap call to find out.
+	log.Printf("Checking bpfman is reachable at %s\n", configMgmt.DefaultPath)
+	listCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	_, err = c.List(listCtx, &gobpfman.ListRequest{BpfmanProgramsOnly: proto.Bool(true)})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("bpfman is not reachable, is bpfman-rpc running? %v", err)
+	}
+
+	// 2. Load the XDP counter. The metadata identifies this run, so the
+	//    program can be found and unloaded if the Load call is abandoned
+	//    after bpfman has already loaded it.
+	metadata := map[string]string{
+		"owner":          "quickstart",
+		"quickstart-run": fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano()),
+	}
+	log.Printf("Loading %s on %s\n", image.Reference, opts.iface)
+	loadCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	res, err := c.Load(loadCtx, &gobpfman.LoadRequest{
+		Bytecode:    image.BytecodeLocation(),
+		Name:        BpfProgramName,
+		ProgramType: *bpfmanHelpers.Xdp.Uint32(),
+		Attach: &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_XdpAttachInfo{
+				XdpAttachInfo: &gobpfman.XDPAttachInfo{
+					Priority: int32(opts.priority),
+					Iface:    opts.iface,
+				},
+			},
+		},
+		Metadata: metadata,
+	})
+	cancel()
+	if err != nil {
+		unloadByMetadata(c, metadata, opts.timeout)
+		return fmt.Errorf("failed to load program: %v", err)
+	}
+	if res.GetKernelInfo() == nil {
+		unloadByMetadata(c, metadata, opts.timeout)
+		return fmt.Errorf("kernelInfo not returned in LoadResponse")
+	}
+	progId := res.GetKernelInfo().GetId()
+	log.Printf("Program registered with id %d\n", progId)
+
+	// 3. Unload the program again however we exit. ctx may already be
+	//    cancelled by then, so use a fresh one.
+	defer func() {
+		log.Printf("Unloading Program: %d\n", progId)
+		unloadCtx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+		defer cancel()
+		if _, err := c.Unload(unloadCtx, &gobpfman.UnloadRequest{Id: progId}); err != nil {
+			log.Printf("failed to unload program %d: %v", progId, err)
+		}
+	}()
+
+	// 4. Wait until the program is listed by bpfman and its map is pinned
+	waitCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	statsMap, err := waitForMap(waitCtx, c, progId)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer statsMap.Close()
+
+	// 5. Print the counters
+	log.Printf("Program is ready, printing counters for %s\n\n", opts.duration)
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+	done := time.After(opts.duration)
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("interrupted")
+		case <-done:
+			return nil
+		case <-ticker.C:
+			packets, bytes, err := lookupCounters(statsMap)
+			if err != nil {
+				return err
+			}
+			log.Printf("%d packets received\n", packets)
+			log.Printf("%d bytes received\n\n", bytes)
+		}
+	}
+}
+
+// unloadByMetadata unloads any program bpfman loaded with metadata. It is
+// used when a Load call fails on the client side, e.g. because it timed out
+// or was interrupted, since bpfman may still have completed the load.
+func unloadByMetadata(c gobpfman.BpfmanClient, metadata map[string]string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	res, err := c.List(ctx, &gobpfman.ListRequest{
+		BpfmanProgramsOnly: proto.Bool(true),
+		MatchMetadata:      metadata,
+	})
+	if err != nil {
+		log.Printf("failed to check for a partially loaded program: %v", err)
+		return
+	}
+	for _, result := range res.GetResults() {
+		id := result.GetKernelInfo().GetId()
+		log.Printf("Unloading Program: %d\n", id)
+		if _, err := c.Unload(ctx, &gobpfman.UnloadRequest{Id: id}); err != nil {
+			log.Printf("failed to unload program %d: %v", id, err)
+		}
+	}
+}
+
+// waitForMap polls bpfman for the program until its stats map can be opened
+// or ctx is done.
+func waitForMap(ctx context.Context, c gobpfman.Getter, progId uint32) (*ebpf.Map, error) {
+	for {
+		mapPath, err := configMgmt.RetrieveMapPinPath(ctx, c, uint(progId), BpfProgramMapIndex)
+		if err == nil {
+			var statsMap *ebpf.Map
+			statsMap, err = ebpf.LoadPinnedMap(mapPath, nil)
+			if err == nil {
+				return statsMap, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("program %d not ready: %v", progId, err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func lookupCounters(statsMap *ebpf.Map) (uint64, uint64, error) {
+	key := uint32(XDP_ACT_OK)
+	var stats []Stats
+	var totalPackets uint64
+	var totalBytes uint64
+
+	if err := statsMap.Lookup(&key, &stats); err != nil {
+		return 0, 0, fmt.Errorf("map lookup failed: %v", err)
+	}
+
+	for _, cpuStat := range stats {
+		totalPackets += cpuStat.Packets
+		totalBytes += cpuStat.Bytes
+	}
+
+	return totalPackets, totalBytes, nil
+}
@@ -147,6 +147,12 @@ func processKprobe(cancelCtx context.Context, paramData *configMgmt.ParameterDat
 			}
 
 			log.Printf("Kprobe: count: %d\n", totalCount)
+
+			if err := output.Report("go-app-counter-kprobe", map[string]uint64{
+				"count": totalCount,
+			}); err != nil {
+				log.Print(err)
+			}
 		}
 	}
 }
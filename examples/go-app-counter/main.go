@@ -13,6 +13,7 @@ import (
 
 	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
 	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+	statsOutput "github.com/bpfman/bpfman/examples/pkg/stats-output"
 	"google.golang.org/grpc"
 )
 
@@ -24,6 +25,7 @@ const (
 var appMutex = &sync.Mutex{}
 var c gobpfman.BpfmanClient
 var ctx context.Context
+var output statsOutput.Output
 
 //go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -no-strip -cflags "-O2 -g -Wall" bpf ./bpf/app_counter.c -- -I.:/usr/include/bpf:/usr/include/linux
 
@@ -38,6 +40,13 @@ func main() {
 		return
 	}
 
+	output, err = statsOutput.New(paramData.Output, paramData.StatsdAddr)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer output.Close()
+
 	// If not running on Kubernetes, create connection to bpfman
 	if !paramData.CrdFlag {
 		ctx = context.Background()
@@ -94,7 +103,7 @@ func main() {
 	// Listen for interrupt signal to gracefully shut down the goroutines
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	<-stop
+	configMgmt.WaitForExit(stop, paramData.Ttl)
 
 	// Cancel the context, signaling all goroutines to stop
 	log.Printf("Calling cancel()...\n")
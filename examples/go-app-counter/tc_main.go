@@ -168,6 +168,13 @@ func processTC(cancelCtx context.Context, paramData *configMgmt.ParameterData) {
 
 			log.Printf("TC: %d packets received %s\n", totalPackets, action)
 			log.Printf("TC: %d bytes received %s\n", totalBytes, action)
+
+			if err := output.Report("go-app-counter-tc", map[string]uint64{
+				"packets": totalPackets,
+				"bytes":   totalBytes,
+			}); err != nil {
+				log.Print(err)
+			}
 		}
 	}
 }
@@ -163,6 +163,12 @@ func processTracepoint(cancelCtx context.Context, paramData *configMgmt.Paramete
 			}
 
 			log.Printf("Tracepoint: SIGUSR1 signal count: %d\n", totalCalls)
+
+			if err := output.Report("go-app-counter-tracepoint", map[string]uint64{
+				"calls": totalCalls,
+			}); err != nil {
+				log.Print(err)
+			}
 		}
 	}
 }
@@ -150,6 +150,12 @@ func processUprobe(cancelCtx context.Context, paramData *configMgmt.ParameterDat
 			}
 
 			log.Printf("Uprobe: count: %d\n", totalCount)
+
+			if err := output.Report("go-app-counter-uprobe", map[string]uint64{
+				"count": totalCount,
+			}); err != nil {
+				log.Print(err)
+			}
 		}
 	}
 }
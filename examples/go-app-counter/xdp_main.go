@@ -155,6 +155,13 @@ func processXdp(cancelCtx context.Context, paramData *configMgmt.ParameterData)
 
 			log.Printf("XDP: %d packets received\n", totalPackets)
 			log.Printf("XDP: %d bytes received\n", totalBytes)
+
+			if err := output.Report("go-app-counter-xdp", map[string]uint64{
+				"packets": totalPackets,
+				"bytes":   totalBytes,
+			}); err != nil {
+				log.Print(err)
+			}
 		}
 	}
 }
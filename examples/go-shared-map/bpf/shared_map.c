@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: (GPL-2.0-only OR BSD-2-Clause)
+// Copyright Authors of bpfman
+
+#include <linux/bpf.h>
+#include <linux/types.h>
+
+#include <bpf/bpf_helpers.h>
+
+/* Both programs below update the same map. bpfman loads the XDP program
+ * first, which creates and pins the map, and then loads the kprobe program
+ * with its MapOwnerId set to the XDP program's id so it reuses that map.
+ */
+
+#define SHARED_STATS_XDP 0
+#define SHARED_STATS_KPROBE 1
+#define SHARED_STATS_MAX 2
+
+struct datarec {
+  __u64 counter;
+} datarec;
+
+struct {
+  __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+  __type(key, __u32);
+  __type(value, datarec);
+  __uint(max_entries, SHARED_STATS_MAX);
+  __uint(pinning, LIBBPF_PIN_BY_NAME);
+} shared_stats_map SEC(".maps");
+
+static __always_inline void shared_stats_inc(__u32 index) {
+  struct datarec *rec = bpf_map_lookup_elem(&shared_stats_map, &index);
+  if (!rec)
+    return;
+
+  rec->counter++;
+}
+
+SEC("xdp")
+int xdp_shared(struct xdp_md *ctx) {
+  shared_stats_inc(SHARED_STATS_XDP);
+
+  return XDP_PASS;
+}
+
+SEC("kprobe/kprobe_shared")
+int kprobe_shared(struct pt_regs *ctx) {
+  shared_stats_inc(SHARED_STATS_KPROBE);
+
+  return 0;
+}
+
+char _license[] SEC("license") = "Dual BSD/GPL";
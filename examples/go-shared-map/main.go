@@ -0,0 +1,142 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+	statsOutput "github.com/bpfman/bpfman/examples/pkg/stats-output"
+)
+
+const (
+	DefaultByteCodeFile = "bpf_bpfel.o"
+	BpfProgramMapIndex  = "shared_stats_map"
+)
+
+// Indexes into shared_stats_map, matching SHARED_STATS_* in bpf/shared_map.c.
+const (
+	SharedStatsXdp = iota
+	SharedStatsKprobe
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -no-strip -cflags "-O2 -g -Wall" bpf ./bpf/shared_map.c -- -I.:/usr/include/bpf:/usr/include/linux
+func main() {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	// Parse Input Parameters (CmdLine and Config File)
+	paramData, err := configMgmt.ParseParamData(configMgmt.ProgTypeXdp, DefaultByteCodeFile)
+	if err != nil {
+		log.Printf("error processing parameters: %v\n", err)
+		return
+	}
+
+	output, err := statsOutput.New(paramData.Output, paramData.StatsdAddr)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer output.Close()
+
+	// This example loads both the map owner and the map user itself, so it
+	// has no Kubernetes deployment and can't join an existing map owner.
+	if paramData.CrdFlag {
+		log.Printf("\"crd\" is not supported by go-shared-map\n")
+		return
+	}
+	if paramData.MapOwnerId != 0 {
+		log.Printf("\"map_owner_id\" is not supported by go-shared-map\n")
+		return
+	}
+
+	ctx := context.Background()
+
+	conn, err := configMgmt.CreateConnection(ctx)
+	if err != nil {
+		log.Printf("failed to create client connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	c := gobpfman.NewBpfmanClient(conn)
+
+	// If the bytecode src is a Program ID, it is the id of an XDP program
+	// previously loaded by this example, so the requests are not used.
+	xdpLoadRequest := &gobpfman.LoadRequest{
+		Bytecode:    paramData.BytecodeSource,
+		Name:        "xdp_shared",
+		ProgramType: *bpfmanHelpers.Xdp.Uint32(),
+		Attach: &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_XdpAttachInfo{
+				XdpAttachInfo: &gobpfman.XDPAttachInfo{
+					Priority: int32(paramData.Priority),
+					Iface:    paramData.Iface,
+				},
+			},
+		},
+	}
+	kprobeLoadRequest := &gobpfman.LoadRequest{
+		Bytecode:    paramData.BytecodeSource,
+		Name:        "kprobe_shared",
+		ProgramType: *bpfmanHelpers.Kprobe.Uint32(),
+		Attach: &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_KprobeAttachInfo{
+				KprobeAttachInfo: &gobpfman.KprobeAttachInfo{
+					FnName: "try_to_wake_up",
+				},
+			},
+		},
+	}
+
+	// Load the XDP program as the map owner, then the kprobe program
+	// as a user of its maps, and open the shared stats map
+	sharedMap, err := configMgmt.OpenSharedMap(ctx, c, paramData, BpfProgramMapIndex, xdpLoadRequest, kprobeLoadRequest)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer func() {
+		if err := sharedMap.Close(ctx); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	ticker := time.NewTicker(3 * time.Second)
+	go func() {
+		for range ticker.C {
+			xdpCount, err := configMgmt.LookupPerCpuCounter(sharedMap.Map, SharedStatsXdp)
+			if err != nil {
+				log.Print(err)
+				return
+			}
+			kprobeCount, err := configMgmt.LookupPerCpuCounter(sharedMap.Map, SharedStatsKprobe)
+			if err != nil {
+				log.Print(err)
+				return
+			}
+
+			log.Printf("XDP: %d packets received\n", xdpCount)
+			log.Printf("Kprobe: %d calls\n\n", kprobeCount)
+
+			if err := output.Report("go-shared-map", map[string]uint64{
+				"xdp_packets":  xdpCount,
+				"kprobe_calls": kprobeCount,
+			}); err != nil {
+				log.Print(err)
+			}
+		}
+	}()
+
+	configMgmt.WaitForExit(stop, paramData.Ttl)
+
+	log.Printf("Exiting...\n")
+}
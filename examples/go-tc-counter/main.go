@@ -15,6 +15,7 @@ import (
 	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
 	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
 	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+	statsOutput "github.com/bpfman/bpfman/examples/pkg/stats-output"
 	"github.com/cilium/ebpf"
 )
 
@@ -48,6 +49,13 @@ func main() {
 		return
 	}
 
+	output, err := statsOutput.New(paramData.Output, paramData.StatsdAddr)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer output.Close()
+
 	var action string
 	var direction bpfmanHelpers.TcProgramDirection
 	if paramData.Direction == configMgmt.TcDirectionIngress {
@@ -132,6 +140,11 @@ func main() {
 				return
 			}
 			log.Printf("Program registered with id %d\n", paramData.ProgId)
+			if params, err := configMgmt.AttachParams(res.GetInfo()); err == nil {
+				log.Printf("Attach parameters: %v\n", params)
+			} else {
+				log.Print(err)
+			}
 
 			// 2. Set up defer to unload program when this is closed
 			defer func(id uint) {
@@ -158,6 +171,12 @@ func main() {
 				conn.Close()
 			}(paramData.ProgId)
 
+			if params, err := configMgmt.RetrieveAttachParams(ctx, c, paramData.ProgId); err == nil {
+				log.Printf("Attach parameters: %v\n", params)
+			} else {
+				log.Print(err)
+			}
+
 			// 3. Get access to our map
 			mapPath, err = configMgmt.RetrieveMapPinPath(ctx, c, paramData.ProgId, "tc_stats_map")
 			if err != nil {
@@ -181,6 +200,17 @@ func main() {
 
 	ticker := time.NewTicker(3 * time.Second)
 	go func() {
+		if paramData.NumaAffinity {
+			node, err := configMgmt.PinToIfaceNumaNode(paramData.Iface)
+			if err != nil {
+				log.Print(err)
+			} else if node == configMgmt.NumaNodeUnknown {
+				log.Printf("%s has no NUMA locality, not pinning\n", paramData.Iface)
+			} else {
+				log.Printf("Reading maps from NUMA node %d\n", node)
+			}
+		}
+
 		for range ticker.C {
 			key := uint32(TC_ACT_OK)
 			var stats []Stats
@@ -200,10 +230,17 @@ func main() {
 
 			log.Printf("%d packets %s\n", totalPackets, action)
 			log.Printf("%d bytes %s\n\n", totalBytes, action)
+
+			if err := output.Report(TcProgramName, map[string]uint64{
+				"packets": totalPackets,
+				"bytes":   totalBytes,
+			}); err != nil {
+				log.Print(err)
+			}
 		}
 	}()
 
-	<-stop
+	configMgmt.WaitForExit(stop, paramData.Ttl)
 
 	log.Printf("Exiting...\n")
 }
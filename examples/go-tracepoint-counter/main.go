@@ -15,6 +15,7 @@ import (
 	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
 	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
 	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+	statsOutput "github.com/bpfman/bpfman/examples/pkg/stats-output"
 	"github.com/cilium/ebpf"
 )
 
@@ -44,6 +45,13 @@ func main() {
 		return
 	}
 
+	output, err := statsOutput.New(paramData.Output, paramData.StatsdAddr)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer output.Close()
+
 	// determine the path to the tracepoint_stats_map, whether provided via CRD
 	// or BPFMAN or otherwise.
 	var mapPath string
@@ -196,10 +204,16 @@ func main() {
 			}
 
 			log.Printf("SIGUSR1 signal count: %d\n", totalCalls)
+
+			if err := output.Report(TracepointProgramName, map[string]uint64{
+				"calls": totalCalls,
+			}); err != nil {
+				log.Print(err)
+			}
 		}
 	}()
 
-	<-stop
+	configMgmt.WaitForExit(stop, paramData.Ttl)
 
 	log.Printf("Exiting...\n")
 }
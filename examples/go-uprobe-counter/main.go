@@ -15,6 +15,7 @@ import (
 	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
 	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
 	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+	statsOutput "github.com/bpfman/bpfman/examples/pkg/stats-output"
 	"github.com/cilium/ebpf"
 )
 
@@ -44,6 +45,13 @@ func main() {
 		return
 	}
 
+	output, err := statsOutput.New(paramData.Output, paramData.StatsdAddr)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer output.Close()
+
 	// determine the path to the uprobe_stats_map, whether provided via CRD
 	// or BPFMAN or otherwise.
 	var mapPath string
@@ -186,10 +194,16 @@ func main() {
 			}
 
 			log.Printf("Uprobe count: %d\n", totalCount)
+
+			if err := output.Report(UprobeProgramName, map[string]uint64{
+				"count": totalCount,
+			}); err != nil {
+				log.Print(err)
+			}
 		}
 	}()
 
-	<-stop
+	configMgmt.WaitForExit(stop, paramData.Ttl)
 
 	log.Printf("Exiting...\n")
 }
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: (GPL-2.0-only OR BSD-2-Clause)
+// Copyright Authors of bpfman
+
+#include <linux/bpf.h>
+#include <linux/if_ether.h>
+#include <linux/in.h>
+#include <linux/ip.h>
+#include <linux/pkt_cls.h>
+#include <linux/types.h>
+
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_helpers.h>
+
+/* The XDP program classifies packets and records a mark for each IPv4
+ * source address in chain_marks_map. The TC ingress program, loaded later on
+ * the same interface, looks the source address up in the same map and acts
+ * on the mark by setting skb->mark. bpfman loads the XDP program first as the
+ * map owner and the TC program with MapOwnerId set, so both share the maps.
+ */
+
+#define CHAIN_MARK_ICMP 1
+#define CHAIN_MARK_UDP 2
+
+#define CHAIN_STATS_XDP_MARKED 0
+#define CHAIN_STATS_TC_ACTED 1
+#define CHAIN_STATS_MAX 2
+
+struct datarec {
+  __u64 counter;
+} datarec;
+
+struct {
+  __uint(type, BPF_MAP_TYPE_LRU_HASH);
+  __type(key, __u32);
+  __type(value, __u32);
+  __uint(max_entries, 1024);
+  __uint(pinning, LIBBPF_PIN_BY_NAME);
+} chain_marks_map SEC(".maps");
+
+struct {
+  __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+  __type(key, __u32);
+  __type(value, datarec);
+  __uint(max_entries, CHAIN_STATS_MAX);
+  __uint(pinning, LIBBPF_PIN_BY_NAME);
+} chain_stats_map SEC(".maps");
+
+static __always_inline void chain_stats_inc(__u32 index) {
+  struct datarec *rec = bpf_map_lookup_elem(&chain_stats_map, &index);
+  if (!rec)
+    return;
+
+  rec->counter++;
+}
+
+static __always_inline struct iphdr *parse_ipv4(void *data, void *data_end) {
+  struct ethhdr *eth = data;
+  if ((void *)(eth + 1) > data_end)
+    return NULL;
+  if (eth->h_proto != bpf_htons(ETH_P_IP))
+    return NULL;
+
+  struct iphdr *ip = (void *)(eth + 1);
+  if ((void *)(ip + 1) > data_end)
+    return NULL;
+
+  return ip;
+}
+
+SEC("xdp")
+int xdp_chain_mark(struct xdp_md *ctx) {
+  void *data = (void *)(long)ctx->data;
+  void *data_end = (void *)(long)ctx->data_end;
+
+  struct iphdr *ip = parse_ipv4(data, data_end);
+  if (!ip)
+    return XDP_PASS;
+
+  __u32 mark;
+  switch (ip->protocol) {
+  case IPPROTO_ICMP:
+    mark = CHAIN_MARK_ICMP;
+    break;
+  case IPPROTO_UDP:
+    mark = CHAIN_MARK_UDP;
+    break;
+  default:
+    return XDP_PASS;
+  }
+
+  __u32 saddr = ip->saddr;
+  bpf_map_update_elem(&chain_marks_map, &saddr, &mark, BPF_ANY);
+  chain_stats_inc(CHAIN_STATS_XDP_MARKED);
+
+  return XDP_PASS;
+}
+
+SEC("classifier/chain_act")
+int tc_chain_act(struct __sk_buff *skb) {
+  void *data = (void *)(long)skb->data;
+  void *data_end = (void *)(long)skb->data_end;
+
+  struct iphdr *ip = parse_ipv4(data, data_end);
+  if (!ip)
+    return TC_ACT_OK;
+
+  __u32 saddr = ip->saddr;
+  __u32 *mark = bpf_map_lookup_elem(&chain_marks_map, &saddr);
+  if (!mark)
+    return TC_ACT_OK;
+
+  skb->mark = *mark;
+  chain_stats_inc(CHAIN_STATS_TC_ACTED);
+
+  return TC_ACT_OK;
+}
+
+char _license[] SEC("license") = "Dual BSD/GPL";
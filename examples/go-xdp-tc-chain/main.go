@@ -0,0 +1,145 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	bpfmanHelpers "github.com/bpfman/bpfman-operator/pkg/helpers"
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	configMgmt "github.com/bpfman/bpfman/examples/pkg/config-mgmt"
+	statsOutput "github.com/bpfman/bpfman/examples/pkg/stats-output"
+)
+
+const (
+	DefaultByteCodeFile = "bpf_bpfel.o"
+	BpfProgramMapIndex  = "chain_stats_map"
+)
+
+// Indexes into chain_stats_map, matching CHAIN_STATS_* in bpf/xdp_tc_chain.c.
+const (
+	ChainStatsXdpMarked = iota
+	ChainStatsTcActed
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -no-strip -cflags "-O2 -g -Wall" bpf ./bpf/xdp_tc_chain.c -- -I.:/usr/include/bpf:/usr/include/linux
+func main() {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	// Parse Input Parameters (CmdLine and Config File)
+	paramData, err := configMgmt.ParseParamData(configMgmt.ProgTypeXdp, DefaultByteCodeFile)
+	if err != nil {
+		log.Printf("error processing parameters: %v\n", err)
+		return
+	}
+
+	output, err := statsOutput.New(paramData.Output, paramData.StatsdAddr)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer output.Close()
+
+	// This example loads both ends of the chain itself, so it has no
+	// Kubernetes deployment and can't join an existing map owner.
+	if paramData.CrdFlag {
+		log.Printf("\"crd\" is not supported by go-xdp-tc-chain\n")
+		return
+	}
+	if paramData.MapOwnerId != 0 {
+		log.Printf("\"map_owner_id\" is not supported by go-xdp-tc-chain\n")
+		return
+	}
+
+	ctx := context.Background()
+
+	conn, err := configMgmt.CreateConnection(ctx)
+	if err != nil {
+		log.Printf("failed to create client connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	c := gobpfman.NewBpfmanClient(conn)
+
+	// If the bytecode src is a Program ID, it is the id of an XDP program
+	// previously loaded by this example, so the requests are not used.
+	xdpLoadRequest := &gobpfman.LoadRequest{
+		Bytecode:    paramData.BytecodeSource,
+		Name:        "xdp_chain_mark",
+		ProgramType: *bpfmanHelpers.Xdp.Uint32(),
+		Attach: &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_XdpAttachInfo{
+				XdpAttachInfo: &gobpfman.XDPAttachInfo{
+					Priority: int32(paramData.Priority),
+					Iface:    paramData.Iface,
+				},
+			},
+		},
+	}
+	tcLoadRequest := &gobpfman.LoadRequest{
+		Bytecode:    paramData.BytecodeSource,
+		Name:        "tc_chain_act",
+		ProgramType: *bpfmanHelpers.Tc.Uint32(),
+		Attach: &gobpfman.AttachInfo{
+			Info: &gobpfman.AttachInfo_TcAttachInfo{
+				TcAttachInfo: &gobpfman.TCAttachInfo{
+					Priority:  int32(paramData.Priority),
+					Iface:     paramData.Iface,
+					Direction: bpfmanHelpers.Ingress.String(),
+				},
+			},
+		},
+	}
+
+	// Load the XDP program as the owner of chain_marks_map and
+	// chain_stats_map, then the TC program as a user of both, and open
+	// chain_stats_map
+	sharedMap, err := configMgmt.OpenSharedMap(ctx, c, paramData, BpfProgramMapIndex, xdpLoadRequest, tcLoadRequest)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer func() {
+		if err := sharedMap.Close(ctx); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	ticker := time.NewTicker(3 * time.Second)
+	go func() {
+		for range ticker.C {
+			markedCount, err := configMgmt.LookupPerCpuCounter(sharedMap.Map, ChainStatsXdpMarked)
+			if err != nil {
+				log.Print(err)
+				return
+			}
+			actedCount, err := configMgmt.LookupPerCpuCounter(sharedMap.Map, ChainStatsTcActed)
+			if err != nil {
+				log.Print(err)
+				return
+			}
+
+			log.Printf("XDP: %d packets marked\n", markedCount)
+			log.Printf("TC: %d packets acted on\n\n", actedCount)
+
+			if err := output.Report("go-xdp-tc-chain", map[string]uint64{
+				"xdp_marked": markedCount,
+				"tc_acted":   actedCount,
+			}); err != nil {
+				log.Print(err)
+			}
+		}
+	}()
+
+	configMgmt.WaitForExit(stop, paramData.Ttl)
+
+	log.Printf("Exiting...\n")
+}
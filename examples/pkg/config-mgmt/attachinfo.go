@@ -0,0 +1,107 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configMgmt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+)
+
+// RetrieveAttachParams gets a program from bpfman and returns the attach
+// parameters bpfman resolved for it, see AttachParams.
+func RetrieveAttachParams(ctx context.Context, c gobpfman.Getter, progId uint) (map[string]string, error) {
+	getRequest := &gobpfman.GetRequest{
+		Id: uint32(progId),
+	}
+	getResponse, err := c.Get(ctx, getRequest)
+	if err != nil {
+		return nil, err
+	}
+	return AttachParams(getResponse.GetInfo())
+}
+
+// AttachParams flattens the attach info bpfman reports for a program into
+// string key/value pairs, suitable for logging or for recording as
+// annotations. Values come from bpfman's state after the attach, so they
+// include defaults it filled in, such as the dispatcher position or the
+// proceed-on actions.
+func AttachParams(programInfo *gobpfman.ProgramInfo) (map[string]string, error) {
+	if programInfo == nil || programInfo.GetAttach() == nil {
+		return nil, fmt.Errorf("couldn't find attach info in response")
+	}
+
+	params := map[string]string{}
+	switch info := programInfo.GetAttach().GetInfo().(type) {
+	case *gobpfman.AttachInfo_XdpAttachInfo:
+		xdp := info.XdpAttachInfo
+		params["iface"] = xdp.GetIface()
+		params["priority"] = strconv.Itoa(int(xdp.GetPriority()))
+		params["position"] = strconv.Itoa(int(xdp.GetPosition()))
+		params["proceedOn"] = joinInt32(xdp.GetProceedOn())
+	case *gobpfman.AttachInfo_TcAttachInfo:
+		tc := info.TcAttachInfo
+		params["iface"] = tc.GetIface()
+		params["direction"] = tc.GetDirection()
+		params["priority"] = strconv.Itoa(int(tc.GetPriority()))
+		params["position"] = strconv.Itoa(int(tc.GetPosition()))
+		params["proceedOn"] = joinInt32(tc.GetProceedOn())
+	case *gobpfman.AttachInfo_TracepointAttachInfo:
+		params["tracepoint"] = info.TracepointAttachInfo.GetTracepoint()
+	case *gobpfman.AttachInfo_KprobeAttachInfo:
+		kprobe := info.KprobeAttachInfo
+		params["fnName"] = kprobe.GetFnName()
+		params["offset"] = strconv.FormatUint(kprobe.GetOffset(), 10)
+		params["retprobe"] = strconv.FormatBool(kprobe.GetRetprobe())
+		if kprobe.ContainerPid != nil {
+			params["containerPid"] = strconv.Itoa(int(kprobe.GetContainerPid()))
+		}
+	case *gobpfman.AttachInfo_UprobeAttachInfo:
+		uprobe := info.UprobeAttachInfo
+		if uprobe.FnName != nil {
+			params["fnName"] = uprobe.GetFnName()
+		}
+		params["offset"] = strconv.FormatUint(uprobe.GetOffset(), 10)
+		params["target"] = uprobe.GetTarget()
+		params["retprobe"] = strconv.FormatBool(uprobe.GetRetprobe())
+		if uprobe.Pid != nil {
+			params["pid"] = strconv.Itoa(int(uprobe.GetPid()))
+		}
+		if uprobe.ContainerPid != nil {
+			params["containerPid"] = strconv.Itoa(int(uprobe.GetContainerPid()))
+		}
+	case *gobpfman.AttachInfo_FentryAttachInfo:
+		params["fnName"] = info.FentryAttachInfo.GetFnName()
+	case *gobpfman.AttachInfo_FexitAttachInfo:
+		params["fnName"] = info.FexitAttachInfo.GetFnName()
+	default:
+		return nil, fmt.Errorf("unknown attach info type %T", info)
+	}
+
+	return params, nil
+}
+
+func joinInt32(values []int32) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(strs, ",")
+}
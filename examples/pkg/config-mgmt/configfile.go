@@ -30,7 +30,10 @@ const (
 	DefaultPath = "/run/bpfman-sock/bpfman.sock"
 )
 
-func CreateConnection(ctx context.Context) (*grpc.ClientConn, error) {
+// CreateConnection connects to the local bpfman socket. Any additional dial
+// options, such as gobpfman.WithLargeResponses, are applied after the
+// transport credentials.
+func CreateConnection(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	var (
 		addr        string
 		local_creds credentials.TransportCredentials
@@ -39,7 +42,8 @@ func CreateConnection(ctx context.Context) (*grpc.ClientConn, error) {
 	addr = fmt.Sprintf("unix://%s", DefaultPath)
 	local_creds = insecure.NewCredentials()
 
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(local_creds))
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(local_creds)}, opts...)
+	conn, err := grpc.NewClient(addr, opts...)
 	if err == nil {
 		return conn, nil
 	}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configMgmt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	"github.com/cilium/ebpf"
+)
+
+// SharedMap is a map shared by a map owner and its users, opened from the
+// owner's map pin path.
+type SharedMap struct {
+	Map *ebpf.Map
+
+	client gobpfman.Unloader
+	// responses are the programs loaded by OpenSharedMap, owner first. It is
+	// empty when the owner was given by program ID.
+	responses []*gobpfman.LoadResponse
+}
+
+// OpenSharedMap opens the map mapName shared by owner and users. Unless
+// paramData selects an already loaded owner by program ID, the programs are
+// first loaded with gobpfman.LoadMapOwnerAndUsers. Close unloads them again.
+func OpenSharedMap(ctx context.Context, c gobpfman.BpfmanClient, paramData ParameterData, mapName string,
+	owner *gobpfman.LoadRequest, users ...*gobpfman.LoadRequest) (*SharedMap, error) {
+	shared := &SharedMap{client: c}
+
+	var mapPath string
+	var err error
+	if paramData.BytecodeSrc != SrcProgId {
+		shared.responses, err = gobpfman.LoadMapOwnerAndUsers(ctx, c, owner, users...)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range shared.responses {
+			log.Printf("Program %s registered with id %d\n",
+				res.GetInfo().GetName(), res.GetKernelInfo().GetId())
+		}
+
+		// The maps are pinned under the owner
+		mapPath, err = CalcMapPinPath(shared.responses[0].GetInfo(), mapName)
+	} else {
+		mapPath, err = RetrieveMapPinPath(ctx, c, paramData.ProgId, mapName)
+	}
+	if err == nil {
+		shared.Map, err = ebpf.LoadPinnedMap(mapPath, nil)
+		if err != nil {
+			err = fmt.Errorf("failed to load pinned map %s: %v", mapPath, err)
+		}
+	}
+	if err != nil {
+		if closeErr := shared.Close(ctx); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+		return nil, err
+	}
+
+	return shared, nil
+}
+
+// Close closes the map and unloads the programs loaded by OpenSharedMap.
+func (s *SharedMap) Close(ctx context.Context) error {
+	if s.Map != nil {
+		s.Map.Close()
+	}
+	for i := len(s.responses) - 1; i >= 0; i-- {
+		log.Printf("Unloading Program: %d\n", s.responses[i].GetKernelInfo().GetId())
+	}
+	return gobpfman.UnloadMapOwnerAndUsers(ctx, s.client, s.responses)
+}
+
+// LookupPerCpuCounter returns the sum over all CPUs of the uint64 counter at
+// index in a per-CPU array map.
+func LookupPerCpuCounter(statsMap *ebpf.Map, index uint32) (uint64, error) {
+	var counters []uint64
+	var total uint64
+
+	if err := statsMap.Lookup(&index, &counters); err != nil {
+		return 0, fmt.Errorf("map lookup failed: %v", err)
+	}
+
+	for _, counter := range counters {
+		total += counter
+	}
+
+	return total, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configMgmt
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// BpfmanMapsDir is where bpfman pins the maps of each program, in a
+	// directory named after the kernel ID of the map owner. See
+	// calc_map_pin_path() in bpfman/src/lib.rs.
+	BpfmanMapsDir = "/run/bpfman/fs/maps"
+
+	// CSI volumeAttributes understood by the bpfman CSI driver.
+	CsiProgramAttribute = "csi.bpfman.io/program"
+	CsiMapsAttribute    = "csi.bpfman.io/maps"
+)
+
+// ErrMapPinPathNotPredictable is returned by PredictMapPinPath when the path
+// depends on the kernel ID the program gets at load time.
+var ErrMapPinPathNotPredictable = errors.New(
+	"map pin path depends on the kernel ID assigned at load; set a map owner ID or a CSI mount path")
+
+// MapPinPathOptions describes how a planned program will be loaded.
+type MapPinPathOptions struct {
+	// MapOwnerId is the kernel ID of an already loaded program whose maps the
+	// planned program will share, as in LoadRequest.MapOwnerId.
+	MapOwnerId uint32
+	// CsiMountPath is the mountPath of the bpfman CSI volume in the pod that
+	// will read the maps. It takes precedence over MapOwnerId because a pod
+	// only sees the maps through the volume.
+	CsiMountPath string
+}
+
+// PredictMapPinPath returns where the map mapName of the program programName
+// will be pinned once it is loaded, so manifests that reference the path can
+// be generated ahead of time.
+//
+// bpfman pins maps under BpfmanMapsDir/<map owner ID>. A program without a
+// map owner owns its maps, and its kernel ID isn't known until it is loaded,
+// so the host path can only be predicted when opts.MapOwnerId is set.
+// Through the CSI driver, maps always appear directly under the volume's
+// mount path.
+func PredictMapPinPath(programName, mapName string, opts MapPinPathOptions) (string, error) {
+	if programName == "" || mapName == "" {
+		return "", fmt.Errorf("program name and map name are required")
+	}
+	if strings.ContainsRune(mapName, '/') {
+		return "", fmt.Errorf("invalid map name %q", mapName)
+	}
+
+	switch {
+	case opts.CsiMountPath != "":
+		return filepath.Join(opts.CsiMountPath, mapName), nil
+	case opts.MapOwnerId != 0:
+		return filepath.Join(BpfmanMapsDir, fmt.Sprint(opts.MapOwnerId), mapName), nil
+	default:
+		return "", fmt.Errorf("program %s: %w", programName, ErrMapPinPathNotPredictable)
+	}
+}
+
+// CsiVolumeAttributes returns the volumeAttributes of a bpfman CSI volume
+// that exposes mapNames of the program programName to a pod.
+func CsiVolumeAttributes(programName string, mapNames ...string) map[string]string {
+	return map[string]string{
+		CsiProgramAttribute: programName,
+		CsiMapsAttribute:    strings.Join(mapNames, ","),
+	}
+}
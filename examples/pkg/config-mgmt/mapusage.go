@@ -0,0 +1,202 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configMgmt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cilium/ebpf"
+)
+
+const (
+	// DefaultMapUsageSampleLimit is the maximum number of keys walked per map
+	// when estimating how many entries are in use.
+	DefaultMapUsageSampleLimit = 100000
+)
+
+// ErrMapNotIterable is recorded in MapUsage.Err for map types, such as ring
+// buffers, queues, stacks and local storage, whose keys can't be walked to
+// count entries.
+var ErrMapNotIterable = errors.New("map keys can't be walked")
+
+// MapUsage describes the capacity and approximate occupancy of a single
+// pinned map.
+type MapUsage struct {
+	Name       string
+	Path       string
+	Type       ebpf.MapType
+	MaxEntries uint32
+	// UsedEntries is the number of keys seen while walking the map. If
+	// Sampled is true the walk stopped at the sample limit and the real
+	// value may be higher.
+	UsedEntries uint32
+	Sampled     bool
+	// MemoryBytes is an estimate of the key and value storage reserved for
+	// MaxEntries, taking per-CPU values into account.
+	MemoryBytes uint64
+	// Err is set when the map could not be sampled, in which case only the
+	// fields that were determined before the failure are valid.
+	Err error
+}
+
+// Utilization returns the fraction of MaxEntries that are in use.
+func (u MapUsage) Utilization() float64 {
+	if u.MaxEntries == 0 {
+		return 0
+	}
+	return float64(u.UsedEntries) / float64(u.MaxEntries)
+}
+
+// SampleMapUsage opens the map pinned at mapPath and reports its capacity,
+// estimated memory footprint and the number of entries in use. At most
+// sampleLimit keys are walked; a sampleLimit of 0 uses
+// DefaultMapUsageSampleLimit.
+func SampleMapUsage(mapPath string, sampleLimit uint32) (MapUsage, error) {
+	var usage MapUsage
+
+	if sampleLimit == 0 {
+		sampleLimit = DefaultMapUsageSampleLimit
+	}
+
+	m, err := ebpf.LoadPinnedMap(mapPath, &ebpf.LoadPinOptions{ReadOnly: true})
+	if err != nil {
+		return usage, fmt.Errorf("failed to load pinned map %s: %v", mapPath, err)
+	}
+	defer m.Close()
+
+	info, err := m.Info()
+	if err != nil {
+		return usage, fmt.Errorf("failed to get info for map %s: %v", mapPath, err)
+	}
+
+	usage = MapUsage{
+		Name:       filepath.Base(mapPath),
+		Path:       mapPath,
+		Type:       info.Type,
+		MaxEntries: info.MaxEntries,
+	}
+
+	valueSize := uint64(info.ValueSize)
+	if isPerCPUMap(info.Type) {
+		cpus, err := ebpf.PossibleCPU()
+		if err != nil {
+			return usage, fmt.Errorf("failed to get possible cpus: %v", err)
+		}
+		valueSize *= uint64(cpus)
+	}
+	usage.MemoryBytes = uint64(info.MaxEntries) * (uint64(info.KeySize) + valueSize)
+
+	// Array maps always have every slot allocated, so there is nothing to walk.
+	if isArrayMap(info.Type) {
+		usage.UsedEntries = info.MaxEntries
+		return usage, nil
+	}
+	if !isIterableMap(info.Type) {
+		usage.Err = ErrMapNotIterable
+		return usage, nil
+	}
+
+	var key interface{}
+	next := make([]byte, info.KeySize)
+	for usage.UsedEntries < sampleLimit {
+		if err := m.NextKey(key, next); err != nil {
+			if errors.Is(err, ebpf.ErrKeyNotExist) {
+				break
+			}
+			return usage, fmt.Errorf("failed to walk map %s: %v", mapPath, err)
+		}
+		usage.UsedEntries++
+		key = append([]byte(nil), next...)
+	}
+	// Only report the count as sampled if the walk was cut short.
+	if usage.UsedEntries >= sampleLimit {
+		if err := m.NextKey(key, next); err == nil {
+			usage.Sampled = true
+		} else if !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return usage, fmt.Errorf("failed to walk map %s: %v", mapPath, err)
+		}
+	}
+
+	return usage, nil
+}
+
+// SampleProgramMapUsage samples every map pinned in a program's map pin
+// directory, as returned in ProgramInfo.MapPinPath, and returns the results
+// sorted by estimated memory footprint, largest first. If topN is non-zero
+// only the first topN maps are returned. A map that can't be sampled doesn't
+// stop the others from being sampled; its error is recorded in MapUsage.Err.
+func SampleProgramMapUsage(mapPinPath string, sampleLimit uint32, topN int) ([]MapUsage, error) {
+	entries, err := os.ReadDir(mapPinPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map pin path %s: %v", mapPinPath, err)
+	}
+
+	var usages []MapUsage
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		mapPath := filepath.Join(mapPinPath, entry.Name())
+		usage, err := SampleMapUsage(mapPath, sampleLimit)
+		if err != nil {
+			usage.Name = entry.Name()
+			usage.Path = mapPath
+			usage.Err = err
+		}
+		usages = append(usages, usage)
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool {
+		return usages[i].MemoryBytes > usages[j].MemoryBytes
+	})
+	if topN > 0 && len(usages) > topN {
+		usages = usages[:topN]
+	}
+
+	return usages, nil
+}
+
+func isPerCPUMap(mapType ebpf.MapType) bool {
+	switch mapType {
+	case ebpf.PerCPUHash, ebpf.PerCPUArray, ebpf.LRUCPUHash, ebpf.PerCPUCGroupStorage:
+		return true
+	}
+	return false
+}
+
+func isArrayMap(mapType ebpf.MapType) bool {
+	switch mapType {
+	case ebpf.Array, ebpf.PerCPUArray:
+		return true
+	}
+	return false
+}
+
+// isIterableMap reports whether the keys of a map type can be walked with
+// NextKey.
+func isIterableMap(mapType ebpf.MapType) bool {
+	switch mapType {
+	case ebpf.RingBuf, ebpf.Queue, ebpf.Stack,
+		ebpf.SkStorage, ebpf.InodeStorage, ebpf.TaskStorage:
+		return false
+	}
+	return true
+}
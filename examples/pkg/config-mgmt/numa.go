@@ -0,0 +1,144 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configMgmt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// NumaNodeUnknown is returned when the kernel doesn't report a NUMA node,
+	// e.g. for virtual interfaces or single node systems.
+	NumaNodeUnknown = -1
+)
+
+// The sysfs directories read for NUMA topology. They are variables so the
+// tests can point them at a fake sysfs.
+var (
+	sysClassNet   = "/sys/class/net"
+	sysNodeDevice = "/sys/devices/system/node"
+)
+
+// IfaceNumaNode returns the NUMA node of the device behind a network
+// interface. Pinning the map reader there keeps the reader thread's own work
+// on the same node as the NIC. It doesn't make per-CPU map reads node local:
+// a lookup copies the slots of every CPU, and XDP and TC programs write them
+// on whichever CPUs handle the interface's RX interrupts.
+func IfaceNumaNode(iface string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(sysClassNet, iface, "device", "numa_node"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NumaNodeUnknown, nil
+		}
+		return NumaNodeUnknown, fmt.Errorf("failed to read NUMA node of %s: %v", iface, err)
+	}
+
+	node, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return NumaNodeUnknown, fmt.Errorf("invalid NUMA node for %s: %v", iface, err)
+	}
+	if node < 0 {
+		return NumaNodeUnknown, nil
+	}
+	return node, nil
+}
+
+// NumaNodeCpus returns the CPUs that belong to a NUMA node.
+func NumaNodeCpus(node int) ([]int, error) {
+	path := filepath.Join(sysNodeDevice, fmt.Sprintf("node%d", node), "cpulist")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CPUs of NUMA node %d: %v", node, err)
+	}
+	return parseCpuList(string(data))
+}
+
+// PinToNumaNode locks the calling goroutine to its OS thread and restricts
+// that thread to the CPUs of the given NUMA node. Call it at the start of the
+// goroutine that reads the maps; other goroutines are not affected.
+func PinToNumaNode(node int) error {
+	cpus, err := NumaNodeCpus(node)
+	if err != nil {
+		return err
+	}
+	if len(cpus) == 0 {
+		return fmt.Errorf("NUMA node %d has no CPUs", node)
+	}
+
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+
+	runtime.LockOSThread()
+	// pid 0 is the calling thread
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to set CPU affinity: %v", err)
+	}
+	return nil
+}
+
+// PinToIfaceNumaNode pins the calling goroutine to the NUMA node of the
+// interface, see PinToNumaNode. It returns the node, or NumaNodeUnknown
+// without pinning if the interface has no NUMA locality.
+func PinToIfaceNumaNode(iface string) (int, error) {
+	node, err := IfaceNumaNode(iface)
+	if err != nil || node == NumaNodeUnknown {
+		return node, err
+	}
+	return node, PinToNumaNode(node)
+}
+
+// parseCpuList parses the kernel's cpulist format, e.g. "0-3,8,10-11\n".
+func parseCpuList(list string) ([]int, error) {
+	var cpus []int
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return cpus, nil
+	}
+	for _, part := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpulist %q: %v", list, err)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("invalid cpulist %q: %v", list, err)
+			}
+		}
+		if end < start {
+			return nil, fmt.Errorf("invalid cpulist %q: bad range %q", list, part)
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
@@ -0,0 +1,135 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configMgmt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCpuList(t *testing.T) {
+	tests := []struct {
+		list    string
+		cpus    []int
+		wantErr bool
+	}{
+		{list: "0-3", cpus: []int{0, 1, 2, 3}},
+		{list: "5", cpus: []int{5}},
+		{list: "0-3,8,10-11", cpus: []int{0, 1, 2, 3, 8, 10, 11}},
+		{list: "0-1,4\n", cpus: []int{0, 1, 4}},
+		{list: "", cpus: nil},
+		{list: "\n", cpus: nil},
+		{list: "0-", wantErr: true},
+		{list: "a", wantErr: true},
+		{list: "1,,2", wantErr: true},
+		{list: "3-1", wantErr: true},
+		{list: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		cpus, err := parseCpuList(tt.list)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", tt.list, cpus)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.list, err)
+			continue
+		}
+		if !reflect.DeepEqual(cpus, tt.cpus) {
+			t.Errorf("%q: got %v, want %v", tt.list, cpus, tt.cpus)
+		}
+	}
+}
+
+// fakeSysfs points sysClassNet and sysNodeDevice at a temporary directory
+// for the duration of the test.
+func fakeSysfs(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	oldNet, oldNode := sysClassNet, sysNodeDevice
+	sysClassNet = filepath.Join(root, "class", "net")
+	sysNodeDevice = filepath.Join(root, "devices", "system", "node")
+	t.Cleanup(func() { sysClassNet, sysNodeDevice = oldNet, oldNode })
+	return root
+}
+
+func writeSysfsFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIfaceNumaNode(t *testing.T) {
+	fakeSysfs(t)
+	for iface, node := range map[string]string{
+		"eth0": "1\n",
+		"eth1": "-1\n",
+		"eth2": "one\n",
+	} {
+		writeSysfsFile(t, filepath.Join(sysClassNet, iface, "device", "numa_node"), node)
+	}
+
+	tests := []struct {
+		iface   string
+		node    int
+		wantErr bool
+	}{
+		{iface: "eth0", node: 1},
+		{iface: "eth1", node: NumaNodeUnknown},
+		// Virtual interfaces such as lo have no device directory.
+		{iface: "lo", node: NumaNodeUnknown},
+		{iface: "eth2", node: NumaNodeUnknown, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		node, err := IfaceNumaNode(tt.iface)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %t", tt.iface, err, tt.wantErr)
+		}
+		if node != tt.node {
+			t.Errorf("%s: got node %d, want %d", tt.iface, node, tt.node)
+		}
+	}
+}
+
+func TestNumaNodeCpus(t *testing.T) {
+	fakeSysfs(t)
+	writeSysfsFile(t, filepath.Join(sysNodeDevice, "node1", "cpulist"), "8-11,24\n")
+
+	cpus, err := NumaNodeCpus(1)
+	if err != nil {
+		t.Fatalf("NumaNodeCpus failed: %v", err)
+	}
+	if want := []int{8, 9, 10, 11, 24}; !reflect.DeepEqual(cpus, want) {
+		t.Errorf("got %v, want %v", cpus, want)
+	}
+	if _, err := NumaNodeCpus(2); err == nil {
+		t.Errorf("expected an error for a node without a cpulist")
+	}
+}
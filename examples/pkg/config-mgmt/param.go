@@ -23,8 +23,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/bpfman/bpfman/clients/gobpfman/bytecode"
 	gobpfman "github.com/bpfman/bpfman/clients/gobpfman/v1"
+	statsOutput "github.com/bpfman/bpfman/examples/pkg/stats-output"
+	"github.com/cilium/ebpf"
 )
 
 const (
@@ -69,13 +73,21 @@ type ParameterData struct {
 	// because isBytecodeLocation_Location is not Public
 	BytecodeSource *gobpfman.BytecodeLocation
 	BytecodeSrc    int
+	// Output selects an additional destination for counter samples.
+	Output     statsOutput.OutputType
+	StatsdAddr string
+	// Ttl is how long the example runs before exiting, unloading its
+	// program if it loaded one. Zero means run until interrupted.
+	Ttl time.Duration
+	// NumaAffinity pins the map reader to the NUMA node of Iface.
+	NumaAffinity bool
 }
 
 func ParseParamData(progType ProgType, bytecodeFile string) (ParameterData, error) {
 	var paramData ParameterData
 	paramData.BytecodeSrc = SrcNone
 
-	var cmdlineImage, cmdlineFile, direction_str, source string
+	var cmdlineImage, cmdlineFile, cmdlineFromPin, direction_str, output_str, source string
 
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 
@@ -84,6 +96,9 @@ func ParseParamData(progType ProgType, bytecodeFile string) (ParameterData, erro
 			"Interface to load bytecode. Optional.")
 		flag.IntVar(&paramData.Priority, "priority", 50,
 			"Priority to load program in bpfman. Optional.")
+		flag.BoolVar(&paramData.NumaAffinity, "numa_affinity", false,
+			"Run the map reader on the CPUs of the interface's NUMA node, to keep its\n"+
+				"work on the same node as the NIC. Optional.")
 	}
 	flag.UintVar(&paramData.ProgId, "id", UnusedProgramId,
 		"Optional Program ID of bytecode that has already been loaded. \"id\" and\n"+
@@ -96,6 +111,12 @@ func ParseParamData(progType ProgType, bytecodeFile string) (ParameterData, erro
 	flag.StringVar(&cmdlineFile, "file", "",
 		"File path of bytecode source. \"file\" and \"image\"/\"id\" are mutually exclusive.\n"+
 			"Example: -file /home/$USER/src/bpfman/examples/go-"+progType.String()+"-counter/bpf_bpfel.o")
+	flag.StringVar(&cmdlineFromPin, "from_pin", "",
+		"bpffs path of a program that is already loaded and pinned. The program's\n"+
+			"kernel ID is looked up from the pin and used as if passed with \"id\", so the\n"+
+			"program must be one bpfman manages, e.g. /run/bpfman/fs/prog_<id>.\n"+
+			"\"from_pin\" and \"id\"/\"file\"/\"image\" are mutually exclusive.\n"+
+			"Example: -from_pin /sys/fs/bpf/my_prog")
 	flag.BoolVar(&paramData.CrdFlag, "crd", false,
 		"Flag to indicate all attributes should be pulled from the BpfProgram CRD.\n"+
 			"Used in Kubernetes deployments and is mutually exclusive with all other\n"+
@@ -107,10 +128,45 @@ func ParseParamData(progType ProgType, bytecodeFile string) (ParameterData, erro
 	flag.IntVar(&paramData.MapOwnerId, "map_owner_id", 0,
 		"Program Id of loaded eBPF program this eBPF program will share a map with.\n"+
 			"Example: -map_owner_id 9785")
+	flag.DurationVar(&paramData.Ttl, "ttl", 0,
+		"Time after which the example exits, for short-lived debugging sessions.\n"+
+			"A program the example loaded itself is unloaded first; with \"id\",\n"+
+			"\"from_pin\" or \"crd\" the program is left loaded. Optional, defaults to\n"+
+			"running until interrupted, and may be combined with \"crd\".\n"+
+			"Example: -ttl 10m")
+	flag.StringVar(&output_str, "output", statsOutput.OutputNone.String(),
+		"Additional destination for counter samples (none, json, syslog, statsd).\n"+
+			"Optional and may be combined with \"crd\".")
+	flag.StringVar(&paramData.StatsdAddr, "statsd_addr", statsOutput.DefaultStatsdAddr,
+		"Address of the statsd server used with \"-output statsd\". Optional.")
 	flag.Parse()
 
+	// "-output" is an additional destination for counter samples. If not
+	// provided, counters are only logged.
+	//    ./go-xdp-counter -iface eth0 -output json
+	output, err := statsOutput.ParseOutputType(output_str)
+	if err != nil {
+		return paramData, err
+	}
+	paramData.Output = output
+
+	// "-ttl" is how long to run before exiting. If not provided, the
+	// example runs until interrupted.
+	//    ./go-kprobe-counter -ttl 10m
+	if paramData.Ttl < 0 {
+		return paramData, fmt.Errorf("invalid ttl (%s). ttl must not be negative", paramData.Ttl)
+	}
+
 	if paramData.CrdFlag {
-		if flag.NFlag() != 1 {
+		// Output selection and the TTL are independent of where the program
+		// is loaded.
+		independentFlags := 0
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "output" || f.Name == "statsd_addr" || f.Name == "ttl" {
+				independentFlags++
+			}
+		})
+		if flag.NFlag()-independentFlags != 1 {
 			return paramData, fmt.Errorf("\"crd\" is mutually exclusive with all other parameters")
 		} else {
 			return paramData, nil
@@ -145,6 +201,21 @@ func ParseParamData(progType ProgType, bytecodeFile string) (ParameterData, erro
 	// "-id" and "-image" are mutually exclusive and "-id" takes precedence.
 	// Parse Commandline first.
 
+	// "-from_pin" is the bpffs path of a program that is already loaded. Its
+	// kernel program ID is resolved here and it is then handled like "-id".
+	//    ./go-xdp-counter -iface eth0 -from_pin /sys/fs/bpf/xdp_stats
+	if len(cmdlineFromPin) != 0 {
+		if paramData.ProgId != UnusedProgramId || len(cmdlineFile) != 0 || len(cmdlineImage) != 0 {
+			return paramData, fmt.Errorf("\"from_pin\" is mutually exclusive with \"id\", \"file\" and \"image\"")
+		}
+
+		progId, err := ProgIdFromPinPath(cmdlineFromPin)
+		if err != nil {
+			return paramData, err
+		}
+		paramData.ProgId = progId
+	}
+
 	// "-id" is a ProgramID for the bytecode that has already loaded into bpfman. If not
 	// provided, check "-file" and "-image".
 	//    ./go-xdp-counter -iface eth0 -id 23415
@@ -164,11 +235,11 @@ func ParseParamData(progType ProgType, bytecodeFile string) (ParameterData, erro
 		//    ./go-xdp-counter -p eth0 -image quay.io/bpfman-bytecode/go-xdp-counter:latest
 		if len(cmdlineImage) != 0 {
 			// "-image" was entered so it is a URL
-			paramData.BytecodeSource = &gobpfman.BytecodeLocation{
-				Location: &gobpfman.BytecodeLocation_Image{Image: &gobpfman.BytecodeImage{
-					Url: cmdlineImage,
-				}},
+			image, err := bytecode.NewImage(cmdlineImage, bytecode.PullAlways, bytecode.TagPolicyAny)
+			if err != nil {
+				return paramData, err
 			}
+			paramData.BytecodeSource = image.BytecodeLocation()
 
 			paramData.BytecodeSrc = SrcImage
 			source = cmdlineImage
@@ -208,7 +279,50 @@ func ParseParamData(progType ProgType, bytecodeFile string) (ParameterData, erro
 	return paramData, nil
 }
 
-func RetrieveMapPinPath(ctx context.Context, c gobpfman.BpfmanClient, progId uint, map_name string) (string, error) {
+// ProgIdFromPinPath returns the kernel program ID of the program pinned at
+// pinPath on a bpffs. The examples look the ID up in bpfman, so it is only
+// useful for programs bpfman manages.
+func ProgIdFromPinPath(pinPath string) (uint, error) {
+	// The kernel rejects BPF_F_RDONLY for program pins, so no options are
+	// passed.
+	prog, err := ebpf.LoadPinnedProgram(pinPath, nil)
+	if err != nil {
+		return UnusedProgramId, fmt.Errorf("failed to load pinned program %s: %v", pinPath, err)
+	}
+	defer prog.Close()
+
+	info, err := prog.Info()
+	if err != nil {
+		return UnusedProgramId, fmt.Errorf("failed to get info for pinned program %s: %v", pinPath, err)
+	}
+
+	id, ok := info.ID()
+	if !ok {
+		return UnusedProgramId, fmt.Errorf("kernel did not return an id for pinned program %s", pinPath)
+	}
+
+	return uint(id), nil
+}
+
+// WaitForExit blocks until a signal is received on stop or, if ttl is
+// non-zero, until ttl has elapsed.
+func WaitForExit(stop <-chan os.Signal, ttl time.Duration) {
+	if ttl == 0 {
+		<-stop
+		return
+	}
+
+	timer := time.NewTimer(ttl)
+	defer timer.Stop()
+
+	select {
+	case <-stop:
+	case <-timer.C:
+		log.Printf("TTL of %s expired\n", ttl)
+	}
+}
+
+func RetrieveMapPinPath(ctx context.Context, c gobpfman.Getter, progId uint, map_name string) (string, error) {
 	var mapPath string
 
 	getRequest := &gobpfman.GetRequest{
@@ -0,0 +1,177 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statsOutput
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultStatsdAddr = "127.0.0.1:8125"
+)
+
+// OutputType selects where counter samples are sent in addition to the
+// examples' regular log output.
+type OutputType int
+
+const (
+	OutputNone OutputType = iota
+	OutputJson
+	OutputSyslog
+	OutputStatsd
+)
+
+func (o OutputType) String() string {
+	switch o {
+	case OutputNone:
+		return "none"
+	case OutputJson:
+		return "json"
+	case OutputSyslog:
+		return "syslog"
+	case OutputStatsd:
+		return "statsd"
+	default:
+		return fmt.Sprintf("OutputType(%d)", int(o))
+	}
+}
+
+// ParseOutputType converts the value of the "-output" flag to an OutputType.
+func ParseOutputType(s string) (OutputType, error) {
+	for o := OutputNone; o <= OutputStatsd; o++ {
+		if s == o.String() {
+			return o, nil
+		}
+	}
+	return OutputNone, fmt.Errorf("invalid output (%s). valid options are none, json, syslog or statsd", s)
+}
+
+// Output receives periodic counter samples from an example program.
+type Output interface {
+	// Report publishes one sample of the named counters for a program.
+	Report(program string, counters map[string]uint64) error
+	Close() error
+}
+
+// New creates an Output of the given type. addr is only used by
+// OutputStatsd; if empty DefaultStatsdAddr is used.
+func New(outputType OutputType, addr string) (Output, error) {
+	switch outputType {
+	case OutputNone:
+		return noneOutput{}, nil
+	case OutputJson:
+		return &jsonOutput{w: os.Stdout}, nil
+	case OutputSyslog:
+		w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "bpfman-example")
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to syslog: %v", err)
+		}
+		return &syslogOutput{w: w}, nil
+	case OutputStatsd:
+		if addr == "" {
+			addr = DefaultStatsdAddr
+		}
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to statsd at %s: %v", addr, err)
+		}
+		return &statsdOutput{conn: conn}, nil
+	default:
+		return nil, fmt.Errorf("unknown output type %d", outputType)
+	}
+}
+
+type noneOutput struct{}
+
+func (noneOutput) Report(string, map[string]uint64) error { return nil }
+func (noneOutput) Close() error                           { return nil }
+
+// jsonOutput writes one JSON object per sample, suitable for log shippers
+// that parse structured stdout.
+type jsonOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+type jsonSample struct {
+	Time     time.Time         `json:"time"`
+	Program  string            `json:"program"`
+	Counters map[string]uint64 `json:"counters"`
+}
+
+func (o *jsonOutput) Report(program string, counters map[string]uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return json.NewEncoder(o.w).Encode(jsonSample{
+		Time:     time.Now(),
+		Program:  program,
+		Counters: counters,
+	})
+}
+
+func (o *jsonOutput) Close() error { return nil }
+
+// syslogOutput sends samples to the local syslog daemon, which on systemd
+// hosts is forwarded to the journal.
+type syslogOutput struct {
+	w *syslog.Writer
+}
+
+func (o *syslogOutput) Report(program string, counters map[string]uint64) error {
+	var fields []string
+	for _, name := range sortedNames(counters) {
+		fields = append(fields, fmt.Sprintf("%s=%d", name, counters[name]))
+	}
+	return o.w.Info(fmt.Sprintf("program=%s %s", program, strings.Join(fields, " ")))
+}
+
+func (o *syslogOutput) Close() error { return o.w.Close() }
+
+// statsdOutput sends each counter as a statsd gauge named
+// "bpfman.<program>.<counter>".
+type statsdOutput struct {
+	conn net.Conn
+}
+
+func (o *statsdOutput) Report(program string, counters map[string]uint64) error {
+	var b strings.Builder
+	for _, name := range sortedNames(counters) {
+		fmt.Fprintf(&b, "bpfman.%s.%s:%d|g\n", program, name, counters[name])
+	}
+	_, err := o.conn.Write([]byte(b.String()))
+	return err
+}
+
+func (o *statsdOutput) Close() error { return o.conn.Close() }
+
+func sortedNames(counters map[string]uint64) []string {
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}